@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Errors returned by APIKeyAuthenticator.Authenticate.
+var (
+	ErrAPIKeyMissing = errors.New("api key missing")
+	ErrAPIKeyInvalid = errors.New("invalid api key")
+)
+
+// APIKey is a configured API key. Only the hex SHA-256 of the key is kept, see HashAPIKey.
+type APIKey struct {
+	Name   string   `json:"name" yaml:"name"`
+	Hash   string   `json:"hash" yaml:"hash"`
+	Roles  []string `json:"roles" yaml:"roles"`
+	Scopes []string `json:"scopes" yaml:"scopes"`
+}
+
+// HashAPIKey returns the hex SHA-256 of key, the form stored in APIKey.Hash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey returns a new random API key and its hash.
+func GenerateAPIKey() (key, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	key = base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashAPIKey(key), nil
+}
+
+// LoadAPIKeysFile reads the keys of a YAML or JSON file of the form {"keys": [APIKey...]}.
+func LoadAPIKeysFile(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Keys []APIKey `yaml:"keys"`
+	}
+	// JSON 是 YAML 的子集，统一用 yaml 解析
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("api keys: %w", err)
+	}
+	return file.Keys, nil
+}
+
+// APIKeyConfig configures an APIKeyAuthenticator.
+type APIKeyConfig struct {
+	Keys []APIKey
+	// Header is the header holding the key, DefaultAPIKeyHeader if empty.
+	Header string
+	// Query names a query parameter holding the key, checked when the header is absent.
+	Query string
+}
+
+var DefaultAPIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator authenticates requests by API key.
+type APIKeyAuthenticator struct {
+	header string
+	query  string
+	keys   map[[sha256.Size]byte]APIKey
+}
+
+func NewAPIKeyAuthenticator(config APIKeyConfig) (*APIKeyAuthenticator, error) {
+	if len(config.Keys) == 0 {
+		return nil, errors.New("api key: no keys")
+	}
+	a := &APIKeyAuthenticator{
+		header: config.Header,
+		query:  config.Query,
+		keys:   make(map[[sha256.Size]byte]APIKey, len(config.Keys)),
+	}
+	if a.header == "" {
+		a.header = DefaultAPIKeyHeader
+	}
+	names := make(map[string]bool, len(config.Keys))
+	for _, key := range config.Keys {
+		if key.Name == "" {
+			return nil, errors.New("api key: key without name")
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("api key: duplicate name %q", key.Name)
+		}
+		names[key.Name] = true
+		hash, err := hex.DecodeString(key.Hash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key %q: hash is not a hex SHA-256", key.Name)
+		}
+		if _, ok := a.keys[[sha256.Size]byte(hash)]; ok {
+			return nil, fmt.Errorf("api key %q: duplicate hash", key.Name)
+		}
+		a.keys[[sha256.Size]byte(hash)] = key
+	}
+	return a, nil
+}
+
+// KeyFromRequest returns the key of r from the configured header or query parameter, in
+// this order, or "" if there is none.
+func (a *APIKeyAuthenticator) KeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(a.header); key != "" {
+		return key
+	}
+	if a.query != "" {
+		return r.URL.Query().Get(a.query)
+	}
+	return ""
+}
+
+// Authenticate implements Authenticator: the principal has the key name as ID and the roles
+// and scopes of the key.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	raw := a.KeyFromRequest(r)
+	if raw == "" {
+		return nil, ErrAPIKeyMissing
+	}
+	// 按哈希查找，比较的是摘要而非明文，不会泄露密钥的时序信息
+	key, ok := a.keys[sha256.Sum256([]byte(raw))]
+	if !ok {
+		return nil, ErrAPIKeyInvalid
+	}
+	return &Principal{ID: key.Name, Roles: key.Roles, Scopes: key.Scopes}, nil
+}
+
+// AuthFunc returns an AuthFunc that accepts requests with a valid key and uses its name as
+// the ID.
+func (a *APIKeyAuthenticator) AuthFunc() AuthFunc {
+	return ToAuthFunc(a)
+}
+
+// APIKeyBeforeRequest returns a client request hook, such as
+// client.HTTPClient.DefaultBeforeRequest, that sends key in header, DefaultAPIKeyHeader if
+// empty.
+func APIKeyBeforeRequest(key, header string) func(req *http.Request) error {
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+	return func(req *http.Request) error {
+		req.Header.Set(header, key)
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	key, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	config := "keys:\n  - name: billing\n    hash: " + hash + "\n    scopes: [invoices:read]\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadAPIKeysFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := NewAPIKeyAuthenticator(APIKeyConfig{Keys: keys, Query: "api_key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		query   string
+		wantErr error
+	}{
+		{"header", key, "", nil},
+		{"query", "", "?api_key=" + key, nil},
+		{"missing", "", "", ErrAPIKeyMissing},
+		{"invalid", key + "x", "", ErrAPIKeyInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			if tt.header != "" {
+				r.Header.Set(DefaultAPIKeyHeader, tt.header)
+			}
+			principal, err := authenticator.Authenticate(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && (principal.ID != "billing" || !principal.HasScope("invoices:read")) {
+				t.Errorf("unexpected principal %+v", principal)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	APIKeyBeforeRequest(key, "")(r)
+	if ok, id := authenticator.AuthFunc()(r); !ok || id != "billing" {
+		t.Errorf("expected billing, got %v %q", ok, id)
+	}
+
+	custom, err := NewAPIKeyAuthenticator(APIKeyConfig{Keys: keys, Header: "Authorization-Key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	APIKeyBeforeRequest(key, "Authorization-Key")(r)
+	if ok, id := custom.AuthFunc()(r); !ok || id != "billing" || r.Header.Get(DefaultAPIKeyHeader) != "" {
+		t.Errorf("expected billing via the custom header, got %v %q", ok, id)
+	}
+}
+
+func TestNewAPIKeyAuthenticatorRejectsBadConfig(t *testing.T) {
+	hash := HashAPIKey("key")
+	for name, keys := range map[string][]APIKey{
+		"no keys":        nil,
+		"no name":        {{Hash: hash}},
+		"plain key":      {{Name: "a", Hash: "key"}},
+		"duplicate name": {{Name: "a", Hash: hash}, {Name: "a", Hash: HashAPIKey("other")}},
+		"duplicate hash": {{Name: "a", Hash: hash}, {Name: "b", Hash: hash}},
+	} {
+		if _, err := NewAPIKeyAuthenticator(APIKeyConfig{Keys: keys}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
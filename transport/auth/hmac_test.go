@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vkviyu/nexus/transport/client"
+)
+
+func TestHMACVerifier(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	verifier, err := NewHMACVerifier(HMACConfig{
+		Keys: []HMACKey{{ID: "svc-a", Secret: "secret", Scopes: []string{"orders:write"}}},
+		Now:  func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(secret string, signedAt time.Time) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/orders?b=2&a=1", strings.NewReader(`{"id":1}`))
+		signer := &HMACSigner{KeyID: "svc-a", Secret: secret, Now: func() time.Time { return signedAt }}
+		if err := signer.Sign(r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := sign("secret", now)
+	principal, err := verifier.Authenticate(r)
+	if err != nil || principal.ID != "svc-a" || !principal.HasScope("orders:write") {
+		t.Fatalf("expected svc-a, got %+v %v", principal, err)
+	}
+	if body, _ := io.ReadAll(r.Body); string(body) != `{"id":1}` {
+		t.Errorf("expected body to stay readable, got %q", body)
+	}
+
+	tampered := sign("secret", now)
+	tampered.RequestURI = "/orders?a=1&b=3"
+	otherHost := sign("secret", now)
+	otherHost.Host = "other.example.com"
+	replayed := sign("secret", now)
+	replay := replayed.Clone(context.Background())
+	replay.Body = io.NopCloser(strings.NewReader(`{"id":1}`))
+	if _, err := verifier.Verify(replayed); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		r       *http.Request
+		wantErr error
+	}{
+		{"unsigned", httptest.NewRequest(http.MethodGet, "/", nil), ErrSignatureMissing},
+		{"wrong secret", sign("other", now), ErrSignatureInvalid},
+		{"tampered", tampered, ErrSignatureInvalid},
+		{"other host", otherHost, ErrSignatureInvalid},
+		{"expired", sign("secret", now.Add(-10*time.Minute)), ErrSignatureExpired},
+		{"replayed", replay, ErrNonceReused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(tt.r); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHMACSignerWithHTTPClient(t *testing.T) {
+	verifier, err := NewHMACVerifier(HMACConfig{Keys: []HMACKey{{ID: "svc-a", Secret: "secret"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 签名覆盖客户端发送的路径，StripPrefix 改写 r.URL 后仍能校验
+	server := httptest.NewServer(http.StripPrefix("/api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, id := verifier.AuthFunc()(r); !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		} else {
+			io.WriteString(w, id)
+		}
+	})))
+	t.Cleanup(server.Close)
+
+	httpClient := client.NewHTTPClient()
+	httpClient.DefaultBeforeRequest = NewHMACSigner("svc-a", "secret").Sign
+	for range 2 {
+		result, err := client.Do(context.Background(), httpClient, &client.Contract[string]{
+			Method: http.MethodPut,
+			URL:    server.URL + "/api/items/1?force=true",
+			Body:   strings.NewReader("payload"),
+		})
+		if err != nil || *result != "svc-a" {
+			t.Fatalf("expected svc-a, got %v %v", result, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+	// oct
+	K string `json:"k"`
+}
+
+// LoadJWKSFile reads the verification keys of a local JWKS file. Keys used for encryption
+// and keys of unsupported types are skipped.
+func LoadJWKSFile(path string) ([]JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS parses the verification keys of a JWKS document, see LoadJWKSFile.
+func ParseJWKS(data []byte) ([]JWTKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	var keys []JWTKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.toJWTKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %q: %w", k.KeyID, err)
+		}
+		// alg 与密钥类型不一致（如 RS384）时跳过
+		if key != nil && (k.Algorithm == "" || k.Algorithm == key.Algorithm) {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) toJWTKey() (*JWTKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &JWTKey{ID: k.KeyID, Algorithm: RS256, Key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if x.BitLen() > 256 || y.BitLen() > 256 {
+			return nil, fmt.Errorf("invalid P-256 point")
+		}
+		point := append([]byte{4}, append(x.FillBytes(make([]byte, 32)), y.FillBytes(make([]byte, 32))...)...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &JWTKey{ID: k.KeyID, Algorithm: ES256, Key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		return &JWTKey{ID: k.KeyID, Algorithm: HS256, Key: secret}, nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
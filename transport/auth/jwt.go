@@ -0,0 +1,435 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWT signing algorithms supported by JWTVerifier.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Errors returned by JWTVerifier.Verify, wrapped in a *TokenError.
+var (
+	ErrTokenMissing     = errors.New("token missing")
+	ErrTokenMalformed   = errors.New("token malformed")
+	ErrTokenAlgorithm   = errors.New("unsupported signing algorithm")
+	ErrTokenKeyNotFound = errors.New("no key for token")
+	ErrTokenSignature   = errors.New("invalid signature")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenNoExpiry    = errors.New("token has no expiration")
+	ErrTokenSubject     = errors.New("token has no subject")
+	ErrTokenNotYetValid = errors.New("token not yet valid")
+	ErrTokenIssuer      = errors.New("invalid issuer")
+	ErrTokenAudience    = errors.New("invalid audience")
+)
+
+// TokenError indicates that a JWT was rejected.
+type TokenError struct {
+	Err error
+}
+
+func (e *TokenError) Error() string {
+	return "jwt: " + e.Err.Error()
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// JWTKey is a verification key: []byte for HS256, *rsa.PublicKey for RS256 and
+// *ecdsa.PublicKey (P-256) for ES256.
+type JWTKey struct {
+	// ID is matched against the kid header of the token when both are set.
+	ID        string
+	Algorithm string
+	Key       any
+}
+
+// Audience is the aud claim, which is either a string or an array of strings.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// NumericDate is a time claim in seconds since the epoch. RFC 7519 allows fractional
+// seconds, which are truncated.
+type NumericDate int64
+
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	if n, err := number.Int64(); err == nil {
+		*d = NumericDate(n)
+		return nil
+	}
+	f, err := number.Float64()
+	if err != nil {
+		return err
+	}
+	*d = NumericDate(math.Floor(f))
+	return nil
+}
+
+// Time returns d as a time.Time.
+func (d NumericDate) Time() time.Time {
+	return time.Unix(int64(d), 0)
+}
+
+// Claims are the claims of a verified JWT.
+type Claims struct {
+	Subject   string      `json:"sub,omitempty"`
+	Issuer    string      `json:"iss,omitempty"`
+	Audience  Audience    `json:"aud,omitempty"`
+	ExpiresAt NumericDate `json:"exp,omitempty"`
+	NotBefore NumericDate `json:"nbf,omitempty"`
+	IssuedAt  NumericDate `json:"iat,omitempty"`
+	ID        string      `json:"jti,omitempty"`
+	// Raw holds every claim of the token, including the registered ones above.
+	Raw map[string]any `json:"-"`
+}
+
+// JWTConfig configures a JWTVerifier.
+type JWTConfig struct {
+	// Keys are the verification keys, see LoadJWKSFile for keys from a JWKS file.
+	Keys []JWTKey
+	// Issuer and Audience are checked when set.
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew in the exp and nbf checks.
+	Leeway time.Duration
+	// RequireExp rejects tokens without an exp claim, nil means true. Point it to false to
+	// accept tokens that never expire.
+	RequireExp *bool
+	// Header is the header holding "Bearer <token>", DefaultJWTHeader if empty.
+	Header string
+	// Cookie and Query name a cookie and a query parameter holding the token, checked in this
+	// order when the header is absent. The query parameter suits WebSocket clients, which
+	// cannot set headers from browsers.
+	Cookie string
+	Query  string
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+	// RolesClaim names the claim holding the roles of the Principal, DefaultRolesClaim if empty.
+	// The scopes are read from "scope" (space separated) or "scp" (array).
+	RolesClaim string
+}
+
+var (
+	DefaultJWTHeader  = "Authorization"
+	DefaultRolesClaim = "roles"
+)
+
+// JWTVerifier verifies JWTs and extracts them from requests.
+type JWTVerifier struct {
+	config JWTConfig
+}
+
+func NewJWTVerifier(config JWTConfig) (*JWTVerifier, error) {
+	if len(config.Keys) == 0 {
+		return nil, errors.New("jwt: no verification keys")
+	}
+	for _, key := range config.Keys {
+		if err := checkKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if config.Header == "" {
+		config.Header = DefaultJWTHeader
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = DefaultRolesClaim
+	}
+	if config.RequireExp == nil {
+		requireExp := true
+		config.RequireExp = &requireExp
+	}
+	return &JWTVerifier{config: config}, nil
+}
+
+func checkKey(key JWTKey) error {
+	var ok bool
+	switch key.Algorithm {
+	case HS256:
+		_, ok = key.Key.([]byte)
+	case RS256:
+		_, ok = key.Key.(*rsa.PublicKey)
+	case ES256:
+		_, ok = key.Key.(*ecdsa.PublicKey)
+	default:
+		return fmt.Errorf("jwt: unsupported algorithm %q for key %q", key.Algorithm, key.ID)
+	}
+	if !ok {
+		return fmt.Errorf("jwt: key %q of type %T does not match algorithm %s", key.ID, key.Key, key.Algorithm)
+	}
+	return nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Verify checks the signature and the exp, nbf, iss and aud claims of token, and rejects
+// tokens without a subject.
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, &TokenError{Err: ErrTokenMalformed}
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, &TokenError{Err: ErrTokenMalformed}
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, &TokenError{Err: ErrTokenMalformed}
+	}
+	if err := v.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, &TokenError{Err: err}
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, &TokenError{Err: ErrTokenMalformed}
+	}
+	if err := decodeSegment(parts[1], &claims.Raw); err != nil {
+		return nil, &TokenError{Err: ErrTokenMalformed}
+	}
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, &TokenError{Err: err}
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (v *JWTVerifier) verifySignature(header jwtHeader, signingInput string, signature []byte) error {
+	if header.Algorithm != HS256 && header.Algorithm != RS256 && header.Algorithm != ES256 {
+		return ErrTokenAlgorithm
+	}
+	found := false
+	for _, key := range v.config.Keys {
+		// 算法必须与密钥一致，防止算法混淆攻击
+		if key.Algorithm != header.Algorithm || (header.KeyID != "" && key.ID != "" && key.ID != header.KeyID) {
+			continue
+		}
+		found = true
+		if verifyWithKey(key, signingInput, signature) {
+			return nil
+		}
+	}
+	if !found {
+		return ErrTokenKeyNotFound
+	}
+	return ErrTokenSignature
+}
+
+func verifyWithKey(key JWTKey, signingInput string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch k := key.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		return hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	}
+	return false
+}
+
+func (v *JWTVerifier) validateClaims(claims *Claims) error {
+	now := v.config.Now()
+	leeway := v.config.Leeway
+	if claims.Subject == "" {
+		return ErrTokenSubject
+	}
+	if claims.ExpiresAt == 0 && *v.config.RequireExp {
+		return ErrTokenNoExpiry
+	}
+	if claims.ExpiresAt != 0 && now.After(claims.ExpiresAt.Time().Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Before(claims.NotBefore.Time().Add(-leeway)) {
+		return ErrTokenNotYetValid
+	}
+	if v.config.Issuer != "" && claims.Issuer != v.config.Issuer {
+		return ErrTokenIssuer
+	}
+	if v.config.Audience != "" && !slices.Contains(claims.Audience, v.config.Audience) {
+		return ErrTokenAudience
+	}
+	return nil
+}
+
+// TokenFromRequest returns the token of r from the configured header, cookie or query
+// parameter, in this order, or "" if there is none.
+func (v *JWTVerifier) TokenFromRequest(r *http.Request) string {
+	if value := r.Header.Get(v.config.Header); value != "" {
+		// 认证方案不区分大小写（RFC 6750）
+		if scheme, token, ok := strings.Cut(value, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	if v.config.Cookie != "" {
+		if cookie, err := r.Cookie(v.config.Cookie); err == nil && cookie.Value != "" {
+			return cookie.Value
+		}
+	}
+	if v.config.Query != "" {
+		return r.URL.Query().Get(v.config.Query)
+	}
+	return ""
+}
+
+// VerifyRequest verifies the token of r, see TokenFromRequest.
+func (v *JWTVerifier) VerifyRequest(r *http.Request) (*Claims, error) {
+	token := v.TokenFromRequest(r)
+	if token == "" {
+		return nil, &TokenError{Err: ErrTokenMissing}
+	}
+	return v.Verify(token)
+}
+
+// AuthFunc returns an AuthFunc that accepts requests with a valid token and uses its subject
+// as the ID.
+func (v *JWTVerifier) AuthFunc() AuthFunc {
+	return func(r *http.Request) (bool, string) {
+		claims, err := v.VerifyRequest(r)
+		if err != nil {
+			return false, ""
+		}
+		return true, claims.Subject
+	}
+}
+
+// Middleware returns an HTTP middleware that rejects requests without a valid token with
+// authFailFunc, DefaultAuthFailFunc if nil, and stores the claims and the principal in the
+// request context, see ClaimsFromContext and PrincipalFromContext.
+func (v *JWTVerifier) Middleware(authFailFunc AuthFailFunc) func(http.Handler) http.Handler {
+	if authFailFunc == nil {
+		authFailFunc = DefaultAuthFailFunc
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := v.VerifyRequest(r)
+			if err != nil {
+				authFailFunc(w, r)
+				return
+			}
+			ctx := ContextWithClaims(r.Context(), claims)
+			next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(ctx, v.principal(claims))))
+		})
+	}
+}
+
+// Authenticate implements Authenticator: the principal has the subject as ID, the roles and
+// scopes of the token and every claim as attributes.
+func (v *JWTVerifier) Authenticate(r *http.Request) (*Principal, error) {
+	claims, err := v.VerifyRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return v.principal(claims), nil
+}
+
+func (v *JWTVerifier) principal(claims *Claims) *Principal {
+	principal := &Principal{
+		ID:         claims.Subject,
+		Roles:      stringsClaim(claims.Raw[v.config.RolesClaim]),
+		Attributes: claims.Raw,
+	}
+	if scope, ok := claims.Raw["scope"].(string); ok {
+		principal.Scopes = strings.Fields(scope)
+	} else {
+		principal.Scopes = stringsClaim(claims.Raw["scp"])
+	}
+	return principal
+}
+
+// stringsClaim converts a claim that is a string or an array of strings.
+func stringsClaim(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// JWTAuthFunc is a shortcut for NewJWTVerifier(config) followed by AuthFunc.
+func JWTAuthFunc(config JWTConfig) (AuthFunc, error) {
+	verifier, err := NewJWTVerifier(config)
+	if err != nil {
+		return nil, err
+	}
+	return verifier.AuthFunc(), nil
+}
+
+type claimsKey struct{}
+
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by JWTVerifier.Middleware.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// signTestToken builds a JWT signed with key, which is a []byte, *rsa.PrivateKey or *ecdsa.PrivateKey.
+func signTestToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	now := time.Unix(1_700_000_000, 0)
+	verifier, err := NewJWTVerifier(JWTConfig{
+		Keys: []JWTKey{
+			{ID: "hs", Algorithm: HS256, Key: secret},
+			{ID: "rs", Algorithm: RS256, Key: &rsaKey.PublicKey},
+			{ID: "es", Algorithm: ES256, Key: &ecKey.PublicKey},
+		},
+		Issuer:   "nexus",
+		Audience: "api",
+		Leeway:   time.Minute,
+		Now:      func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := func(overrides map[string]any) map[string]any {
+		claims := map[string]any{"sub": "alice", "iss": "nexus", "aud": []string{"api", "web"}, "exp": now.Add(time.Hour).Unix(), "role": "admin"}
+		for k, v := range overrides {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"hs256", signTestToken(t, HS256, "hs", secret, valid(nil)), nil},
+		{"rs256", signTestToken(t, RS256, "rs", rsaKey, valid(map[string]any{"aud": "api"})), nil},
+		{"es256", signTestToken(t, ES256, "", ecKey, valid(nil)), nil},
+		{"within leeway", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"exp": now.Add(-30 * time.Second).Unix()})), nil},
+		{"expired", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"exp": now.Add(-time.Hour).Unix()})), ErrTokenExpired},
+		{"not yet valid", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"nbf": now.Add(time.Hour).Unix()})), ErrTokenNotYetValid},
+		{"issuer", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"iss": "other"})), ErrTokenIssuer},
+		{"audience", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"aud": "web"})), ErrTokenAudience},
+		{"wrong secret", signTestToken(t, HS256, "hs", []byte("other"), valid(nil)), ErrTokenSignature},
+		{"unknown kid", signTestToken(t, HS256, "nope", secret, valid(nil)), ErrTokenKeyNotFound},
+		{"none", signTestToken(t, "none", "", nil, valid(nil)), ErrTokenAlgorithm},
+		{"fractional exp", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"exp": float64(now.Unix()) + 0.5})), nil},
+		{"fractional exp expired", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"exp": float64(now.Add(-time.Hour).Unix()) + 0.5})), ErrTokenExpired},
+		{"no exp", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"exp": nil})), ErrTokenNoExpiry},
+		{"no subject", signTestToken(t, HS256, "hs", secret, valid(map[string]any{"sub": ""})), ErrTokenSubject},
+		{"malformed", "a.b", ErrTokenMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && (claims.Subject != "alice" || claims.Raw["role"] != "admin") {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestJWTOptionalExp(t *testing.T) {
+	secret := []byte("secret")
+	requireExp := false
+	verifier, err := NewJWTVerifier(JWTConfig{Keys: []JWTKey{{Algorithm: HS256, Key: secret}}, RequireExp: &requireExp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(signTestToken(t, HS256, "", secret, map[string]any{"sub": "alice"})); err != nil {
+		t.Errorf("expected token without exp to verify, got %v", err)
+	}
+}
+
+func TestJWTAuthFuncAndMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	verifier, err := NewJWTVerifier(JWTConfig{
+		Keys:   []JWTKey{{Algorithm: HS256, Key: secret}},
+		Cookie: "token",
+		Query:  "access_token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signTestToken(t, HS256, "", secret, map[string]any{"sub": "bob", "exp": time.Now().Add(time.Hour).Unix()})
+
+	authFunc := verifier.AuthFunc()
+	requests := map[string]*http.Request{
+		"header": httptest.NewRequest(http.MethodGet, "/", nil),
+		"cookie": httptest.NewRequest(http.MethodGet, "/", nil),
+		"query":  httptest.NewRequest(http.MethodGet, "/?access_token="+token, nil),
+	}
+	requests["header"].Header.Set("Authorization", "bearer "+token)
+	requests["cookie"].AddCookie(&http.Cookie{Name: "token", Value: token})
+	for name, r := range requests {
+		if ok, id := authFunc(r); !ok || id != "bob" {
+			t.Errorf("%s: expected bob, got %v %q", name, ok, id)
+		}
+	}
+	if ok, _ := authFunc(httptest.NewRequest(http.MethodGet, "/", nil)); ok {
+		t.Error("expected request without token to be rejected")
+	}
+
+	handler := verifier.Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFromContext(r.Context())
+		fmt.Fprint(w, claims.Subject)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, requests["header"])
+	if rec.Body.String() != "bob" {
+		t.Errorf("expected claims in context, got %q", rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", rec.Code)
+	}
+}
+
+func TestLoadJWKSFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rs", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode([]byte{1, 0, 1})},
+		{"kty": "EC", "kid": "es", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": encode(rsaKey.N.Bytes()), "e": encode([]byte{1, 0, 1})},
+	}}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadJWKSFile(path)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("expected 2 signing keys, got %d %v", len(keys), err)
+	}
+	verifier, err := NewJWTVerifier(JWTConfig{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{
+		signTestToken(t, RS256, "rs", rsaKey, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}),
+		signTestToken(t, ES256, "es", ecKey, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}),
+	} {
+		if _, err := verifier.Verify(token); err != nil {
+			t.Errorf("expected token to verify with JWKS keys: %v", err)
+		}
+	}
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	secret := []byte("secret")
+	verifier, err := NewJWTVerifier(JWTConfig{Keys: []JWTKey{{Algorithm: HS256, Key: secret}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signTestToken(t, HS256, "", secret, map[string]any{"sub": "carol", "exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"admin"}, "scope": "users:read users:write"})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	principal, err := verifier.Authenticate(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.ID != "carol" || !principal.HasRole("admin") || !principal.HasScope("users:write") {
+		t.Errorf("unexpected principal %+v", principal)
+	}
+	if ok, id := ToAuthFunc(verifier)(r); !ok || id != "carol" {
+		t.Errorf("expected adapted AuthFunc to accept carol, got %v %q", ok, id)
+	}
+	if _, err := FromAuthFunc(func(r *http.Request) (bool, string) { return false, "" }).Authenticate(r); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// ErrUnauthenticated is returned by an Authenticator that rejects a request.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Principal is the authenticated identity of a request or connection.
+type Principal struct {
+	ID         string
+	Roles      []string
+	Scopes     []string
+	Attributes map[string]any
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator authenticates a request, returning an error such as ErrUnauthenticated when
+// the request is rejected.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// AuthenticatorFunc adapts a function to an Authenticator.
+type AuthenticatorFunc func(r *http.Request) (*Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*Principal, error) {
+	return f(r)
+}
+
+// FromAuthFunc adapts an AuthFunc to an Authenticator whose principal only has an ID.
+func FromAuthFunc(authFunc AuthFunc) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (*Principal, error) {
+		ok, id := authFunc(r)
+		if !ok {
+			return nil, ErrUnauthenticated
+		}
+		return &Principal{ID: id}, nil
+	})
+}
+
+// ToAuthFunc adapts an Authenticator to an AuthFunc that reports the principal ID.
+func ToAuthFunc(authenticator Authenticator) AuthFunc {
+	return func(r *http.Request) (bool, string) {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil || principal == nil {
+			return false, ""
+		}
+		return true, principal.ID
+	}
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored by ContextWithPrincipal.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
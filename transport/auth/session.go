@@ -0,0 +1,418 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSessionNotFound is returned when a request has no valid session.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is a server-side session identified by the ID in the session cookie.
+// Anonymous sessions have an empty UserID, see SessionManager.Start.
+type Session struct {
+	ID        string            `json:"id"`
+	UserID    string            `json:"userId,omitempty"`
+	Values    map[string]string `json:"values,omitempty"`
+	CSRFToken string            `json:"csrfToken"`
+	CreatedAt time.Time         `json:"createdAt"`
+	// LastSeenAt drives IdleTimeout; the manager refreshes it at most once per minute, or
+	// once per half IdleTimeout when that is shorter.
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+func (s *Session) clone() *Session {
+	c := *s
+	c.Values = maps.Clone(s.Values)
+	return &c
+}
+
+// SessionStore persists sessions. Get returns nil and no error for unknown IDs; the
+// manager rejects and deletes expired sessions itself.
+type SessionStore interface {
+	Get(id string) (*Session, error)
+	Save(session *Session) error
+	Delete(id string) error
+}
+
+// SessionConfig configures a SessionManager.
+type SessionConfig struct {
+	// Store keeps the sessions, a MemorySessionStore if nil. A MemorySessionStore prunes
+	// sessions with Now and IdleTimeout unless it sets its own.
+	Store SessionStore
+	// CookieName is DefaultSessionCookie if empty, CookiePath "/" if empty.
+	CookieName   string
+	CookiePath   string
+	CookieDomain string
+	// Insecure drops the Secure attribute of the cookie, for local development over HTTP.
+	Insecure bool
+	// SameSite is http.SameSiteLaxMode if zero.
+	SameSite http.SameSite
+	// MaxAge is the absolute lifetime of a session, DefaultSessionMaxAge if zero.
+	MaxAge time.Duration
+	// IdleTimeout expires sessions unused for this long, disabled if zero.
+	IdleTimeout time.Duration
+	// CSRFHeader and CSRFFormField carry the CSRF token of unsafe requests,
+	// DefaultCSRFHeader and DefaultCSRFFormField if empty.
+	CSRFHeader    string
+	CSRFFormField string
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+var (
+	DefaultSessionCookie = "nexus_session"
+	DefaultSessionMaxAge = 24 * time.Hour
+	DefaultCSRFHeader    = "X-CSRF-Token"
+	DefaultCSRFFormField = "csrf_token"
+)
+
+// SessionManager issues session cookies and resolves them to sessions.
+type SessionManager struct {
+	config SessionConfig
+}
+
+func NewSessionManager(config SessionConfig) *SessionManager {
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if config.Store == nil {
+		config.Store = NewMemorySessionStore()
+	}
+	if store, ok := config.Store.(*MemorySessionStore); ok {
+		store.mu.Lock()
+		if store.Now == nil {
+			store.Now = config.Now
+		}
+		if store.IdleTimeout == 0 {
+			store.IdleTimeout = config.IdleTimeout
+		}
+		store.mu.Unlock()
+	}
+	if config.CookieName == "" {
+		config.CookieName = DefaultSessionCookie
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+	if config.MaxAge == 0 {
+		config.MaxAge = DefaultSessionMaxAge
+	}
+	if config.CSRFHeader == "" {
+		config.CSRFHeader = DefaultCSRFHeader
+	}
+	if config.CSRFFormField == "" {
+		config.CSRFFormField = DefaultCSRFFormField
+	}
+	return &SessionManager{config: config}
+}
+
+// Get returns the session of r, or ErrSessionNotFound if the request has no cookie or the
+// session is unknown or expired.
+func (m *SessionManager) Get(r *http.Request) (*Session, error) {
+	if session, ok := SessionFromContext(r.Context()); ok {
+		return session, nil
+	}
+	cookie, err := r.Cookie(m.config.CookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, ErrSessionNotFound
+	}
+	session, err := m.config.Store.Get(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	now := m.config.Now()
+	if m.expired(session, now) {
+		m.config.Store.Delete(session.ID)
+		return nil, ErrSessionNotFound
+	}
+	// 限制写入频率，但间隔需小于 IdleTimeout，否则活跃会话也会过期
+	if m.config.IdleTimeout > 0 && now.Sub(session.LastSeenAt) > min(time.Minute, m.config.IdleTimeout/2) {
+		session.LastSeenAt = now
+		if err := m.config.Store.Save(session); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+func (m *SessionManager) expired(session *Session, now time.Time) bool {
+	return sessionExpired(session, now, m.config.IdleTimeout)
+}
+
+func sessionExpired(session *Session, now time.Time, idleTimeout time.Duration) bool {
+	if !now.Before(session.ExpiresAt) {
+		return true
+	}
+	return idleTimeout > 0 && now.Sub(session.LastSeenAt) > idleTimeout
+}
+
+// Start returns the session of r, creating an anonymous session and setting its cookie
+// when there is none, e.g. to render a login form with a CSRF token.
+func (m *SessionManager) Start(w http.ResponseWriter, r *http.Request) (*Session, error) {
+	session, err := m.Get(r)
+	if !errors.Is(err, ErrSessionNotFound) {
+		return session, err
+	}
+	return m.create(w, "", nil)
+}
+
+// Login starts an authenticated session for userID. The previous session of r is replaced
+// by one with a new ID and CSRF token, keeping its values, so that an identifier planted
+// before login (session fixation) is never authenticated. The new session also replaces
+// the one stored in the request context by Middleware.
+func (m *SessionManager) Login(w http.ResponseWriter, r *http.Request, userID string) (*Session, error) {
+	var values map[string]string
+	if previous, err := m.Get(r); err == nil {
+		values = previous.Values
+		if err := m.config.Store.Delete(previous.ID); err != nil {
+			return nil, err
+		}
+	}
+	session, err := m.create(w, userID, values)
+	if err != nil {
+		return nil, err
+	}
+	setContextSession(r.Context(), session)
+	return session, nil
+}
+
+// Logout deletes the session of r, removes it from the request context and expires its cookie.
+func (m *SessionManager) Logout(w http.ResponseWriter, r *http.Request) error {
+	if session, err := m.Get(r); err == nil {
+		if err := m.config.Store.Delete(session.ID); err != nil {
+			return err
+		}
+	}
+	setContextSession(r.Context(), nil)
+	cookie := m.cookie("")
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+	return nil
+}
+
+// Save persists changes to the values of session.
+func (m *SessionManager) Save(session *Session) error {
+	return m.config.Store.Save(session)
+}
+
+func (m *SessionManager) create(w http.ResponseWriter, userID string, values map[string]string) (*Session, error) {
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	now := m.config.Now()
+	session := &Session{
+		ID:         id,
+		UserID:     userID,
+		Values:     values,
+		CSRFToken:  csrfToken,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(m.config.MaxAge),
+	}
+	if err := m.config.Store.Save(session); err != nil {
+		return nil, err
+	}
+	http.SetCookie(w, m.cookie(id))
+	return session, nil
+}
+
+func (m *SessionManager) cookie(value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    value,
+		Path:     m.config.CookiePath,
+		Domain:   m.config.CookieDomain,
+		MaxAge:   int(m.config.MaxAge / time.Second),
+		Secure:   !m.config.Insecure,
+		HttpOnly: true,
+		SameSite: m.config.SameSite,
+	}
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Authenticate implements Authenticator: the principal has the user ID of the session as
+// ID and its values as attributes. Anonymous sessions are rejected.
+func (m *SessionManager) Authenticate(r *http.Request) (*Principal, error) {
+	session, err := m.Get(r)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID == "" {
+		return nil, ErrUnauthenticated
+	}
+	attributes := make(map[string]any, len(session.Values))
+	for k, v := range session.Values {
+		attributes[k] = v
+	}
+	return &Principal{ID: session.UserID, Attributes: attributes}, nil
+}
+
+// AuthFunc returns an AuthFunc that accepts requests with an authenticated session and uses
+// the session user as the ID.
+func (m *SessionManager) AuthFunc() AuthFunc {
+	return ToAuthFunc(m)
+}
+
+// Middleware returns an HTTP middleware that stores the session of the request, if any, in
+// the request context, see SessionFromContext. Requests without a session are not rejected.
+func (m *SessionManager) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if session, err := m.Get(r); err == nil {
+				r = r.WithContext(ContextWithSession(r.Context(), session))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// VerifyCSRF reports whether r carries the CSRF token of its session in the CSRF header or
+// form field.
+func (m *SessionManager) VerifyCSRF(r *http.Request) bool {
+	session, err := m.Get(r)
+	if err != nil {
+		return false
+	}
+	token := r.Header.Get(m.config.CSRFHeader)
+	if token == "" {
+		token = r.PostFormValue(m.config.CSRFFormField)
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
+
+// CSRFMiddleware returns an HTTP middleware that rejects unsafe requests (other than GET,
+// HEAD, OPTIONS and TRACE) without a valid CSRF token with authFailFunc, or 403 if nil.
+func (m *SessionManager) CSRFMiddleware(authFailFunc AuthFailFunc) func(http.Handler) http.Handler {
+	if authFailFunc == nil {
+		authFailFunc = func(rw http.ResponseWriter, r *http.Request) {
+			http.Error(rw, "Forbidden", http.StatusForbidden)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			default:
+				if !m.VerifyCSRF(r) {
+					authFailFunc(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type sessionKey struct{}
+
+// sessionSlot holds the session of a request context, so that Login and Logout can
+// replace it for the rest of the request.
+type sessionSlot struct {
+	session atomic.Pointer[Session]
+}
+
+func ContextWithSession(ctx context.Context, session *Session) context.Context {
+	slot := &sessionSlot{}
+	slot.session.Store(session)
+	return context.WithValue(ctx, sessionKey{}, slot)
+}
+
+// SessionFromContext returns the session stored by SessionManager.Middleware, or the one
+// started by a later Login in the same request.
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	slot, ok := ctx.Value(sessionKey{}).(*sessionSlot)
+	if !ok {
+		return nil, false
+	}
+	session := slot.session.Load()
+	return session, session != nil
+}
+
+func setContextSession(ctx context.Context, session *Session) {
+	if slot, ok := ctx.Value(sessionKey{}).(*sessionSlot); ok {
+		slot.session.Store(session)
+	}
+}
+
+// MemorySessionStore is an in-memory SessionStore. Sessions are lost on restart.
+// Save prunes sessions that are past ExpiresAt, or unused for IdleTimeout if set.
+type MemorySessionStore struct {
+	// IdleTimeout and Now default to those of the SessionManager using the store.
+	IdleTimeout time.Duration
+	Now         func() time.Time
+
+	sessions  map[string]*Session
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]*Session),
+	}
+}
+
+func (s *MemorySessionStore) Get(id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return session.clone(), nil
+}
+
+func (s *MemorySessionStore) Save(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	// 顺便清理过期会话，避免内存无限增长
+	if now.Sub(s.lastPrune) > time.Minute {
+		for id, stored := range s.sessions {
+			if sessionExpired(stored, now, s.IdleTimeout) {
+				delete(s.sessions, id)
+			}
+		}
+		s.lastPrune = now
+	}
+	s.sessions[session.ID] = session.clone()
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/vkviyu/nexus/database/embedded/badgerdb"
+)
+
+// DefaultSessionKeyPrefix is the key prefix used by BadgerSessionStore.
+var DefaultSessionKeyPrefix = "nexus/session/"
+
+// BadgerSessionStore is a SessionStore persisted in a badgerdb database under Prefix + ID.
+// Entries carry a TTL, so badger removes expired sessions by itself.
+type BadgerSessionStore struct {
+	DB     *badgerdb.DB
+	Prefix string
+}
+
+func NewBadgerSessionStore(db *badgerdb.DB) *BadgerSessionStore {
+	return &BadgerSessionStore{
+		DB:     db,
+		Prefix: DefaultSessionKeyPrefix,
+	}
+}
+
+func (s *BadgerSessionStore) Get(id string) (*Session, error) {
+	var session *Session
+	err := s.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(s.Prefix + id))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			session = &Session{}
+			return json.Unmarshal(val, session)
+		})
+	})
+	return session, err
+}
+
+func (s *BadgerSessionStore) Save(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return s.Delete(session.ID)
+	}
+	return s.DB.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(s.Prefix+session.ID), data).WithTTL(ttl))
+	})
+}
+
+func (s *BadgerSessionStore) Delete(id string) error {
+	return s.DB.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(s.Prefix + id))
+	})
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vkviyu/nexus/database/embedded/bboltdb"
+	"go.etcd.io/bbolt"
+)
+
+// DefaultSessionBucket is the bucket used by BboltSessionStore.
+var DefaultSessionBucket = "nexus_sessions"
+
+// BboltSessionStore is a SessionStore persisted in a bboltdb database, keyed by session ID.
+// bbolt has no expiry, call DeleteExpired periodically to remove abandoned sessions.
+type BboltSessionStore struct {
+	DB     *bboltdb.DB
+	Bucket string
+}
+
+func NewBboltSessionStore(db *bboltdb.DB) *BboltSessionStore {
+	return &BboltSessionStore{
+		DB:     db,
+		Bucket: DefaultSessionBucket,
+	}
+}
+
+func (s *BboltSessionStore) Get(id string) (*Session, error) {
+	var session *Session
+	err := s.DB.NestedViewTransaction([]string{s.Bucket}, func(tx *bbolt.Tx, b *bbolt.Bucket) error {
+		data := b.Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		session = &Session{}
+		return json.Unmarshal(data, session)
+	})
+	return session, err
+}
+
+func (s *BboltSessionStore) Save(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return s.DB.NestedUpdateTransaction([]string{s.Bucket}, func(tx *bbolt.Tx, b *bbolt.Bucket) error {
+		return b.Put([]byte(session.ID), data)
+	})
+}
+
+func (s *BboltSessionStore) Delete(id string) error {
+	return s.DB.NestedUpdateTransaction([]string{s.Bucket}, func(tx *bbolt.Tx, b *bbolt.Bucket) error {
+		return b.Delete([]byte(id))
+	})
+}
+
+// DeleteExpired removes the sessions that expired before now.
+func (s *BboltSessionStore) DeleteExpired(now time.Time) error {
+	return s.DB.NestedUpdateTransaction([]string{s.Bucket}, func(tx *bbolt.Tx, b *bbolt.Bucket) error {
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var session Session
+			if err := json.Unmarshal(v, &session); err != nil || now.After(session.ExpiresAt) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// 遍历期间不能修改桶，先收集再删除
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
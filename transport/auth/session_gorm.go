@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultSessionTable is the table used by GormSessionStore.
+var DefaultSessionTable = "nexus_sessions"
+
+// SessionRecord is the row of a session in GormSessionStore.
+type SessionRecord struct {
+	ID        string    `gorm:"primaryKey;size:64"`
+	Data      []byte    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+// GormSessionStore is a SessionStore persisted with gorm, e.g. in the MySQL database opened
+// by gormdb.Open. Call AutoMigrate to create the table and DeleteExpired periodically to
+// remove abandoned sessions.
+type GormSessionStore struct {
+	DB    *gorm.DB
+	Table string
+}
+
+func NewGormSessionStore(db *gorm.DB) *GormSessionStore {
+	return &GormSessionStore{
+		DB:    db,
+		Table: DefaultSessionTable,
+	}
+}
+
+func (s *GormSessionStore) table() *gorm.DB {
+	return s.DB.Table(s.Table)
+}
+
+func (s *GormSessionStore) AutoMigrate() error {
+	return s.table().AutoMigrate(&SessionRecord{})
+}
+
+func (s *GormSessionStore) Get(id string) (*Session, error) {
+	var record SessionRecord
+	if err := s.table().Where("id = ?", id).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	session := &Session{}
+	if err := json.Unmarshal(record.Data, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *GormSessionStore) Save(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	record := &SessionRecord{ID: session.ID, Data: data, ExpiresAt: session.ExpiresAt}
+	return s.table().Clauses(clause.OnConflict{UpdateAll: true}).Create(record).Error
+}
+
+func (s *GormSessionStore) Delete(id string) error {
+	return s.table().Where("id = ?", id).Delete(&SessionRecord{}).Error
+}
+
+// DeleteExpired removes the sessions that expired before now.
+func (s *GormSessionStore) DeleteExpired(now time.Time) error {
+	return s.table().Where("expires_at < ?", now).Delete(&SessionRecord{}).Error
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/vkviyu/nexus/database/embedded/badgerdb"
+	"github.com/vkviyu/nexus/database/embedded/bboltdb"
+)
+
+// withCookies returns a request carrying the cookies set on rec.
+func withCookies(r *http.Request, rec *httptest.ResponseRecorder) *http.Request {
+	for _, cookie := range rec.Result().Cookies() {
+		r.AddCookie(cookie)
+	}
+	return r
+}
+
+func TestSessionManager(t *testing.T) {
+	now := time.Now()
+	manager := NewSessionManager(SessionConfig{
+		IdleTimeout: 30 * time.Minute,
+		Now:         func() time.Time { return now },
+	})
+
+	// 登录前的匿名会话
+	rec := httptest.NewRecorder()
+	anonymous, err := manager.Start(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie := rec.Result().Cookies()[0]
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("expected a secure cookie, got %+v", cookie)
+	}
+	anonymous.Values = map[string]string{"theme": "dark"}
+	if err := manager.Save(anonymous); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := manager.AuthFunc()(withCookies(httptest.NewRequest(http.MethodGet, "/", nil), rec)); ok {
+		t.Error("expected anonymous session to be rejected")
+	}
+
+	loginRec := httptest.NewRecorder()
+	session, err := manager.Login(loginRec, withCookies(httptest.NewRequest(http.MethodPost, "/login", nil), rec), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.ID == anonymous.ID || session.CSRFToken == anonymous.CSRFToken || session.Values["theme"] != "dark" {
+		t.Errorf("expected rotated session keeping values, got %+v", session)
+	}
+	if _, err := manager.Get(withCookies(httptest.NewRequest(http.MethodGet, "/", nil), rec)); err != ErrSessionNotFound {
+		t.Errorf("expected pre-login session to be gone, got %v", err)
+	}
+	if ok, id := manager.AuthFunc()(withCookies(httptest.NewRequest(http.MethodGet, "/", nil), loginRec)); !ok || id != "alice" {
+		t.Errorf("expected alice, got %v %q", ok, id)
+	}
+
+	now = now.Add(31 * time.Minute)
+	if _, err := manager.Get(withCookies(httptest.NewRequest(http.MethodGet, "/", nil), loginRec)); err != ErrSessionNotFound {
+		t.Errorf("expected idle session to expire, got %v", err)
+	}
+
+	loginRec = httptest.NewRecorder()
+	session, _ = manager.Login(loginRec, httptest.NewRequest(http.MethodPost, "/login", nil), "alice")
+	logoutRec := httptest.NewRecorder()
+	if err := manager.Logout(logoutRec, withCookies(httptest.NewRequest(http.MethodPost, "/logout", nil), loginRec)); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := manager.config.Store.Get(session.ID); stored != nil {
+		t.Error("expected session to be deleted on logout")
+	}
+	if logoutRec.Result().Cookies()[0].MaxAge >= 0 {
+		t.Error("expected cookie to be expired on logout")
+	}
+}
+
+func TestSessionShortIdleTimeout(t *testing.T) {
+	now := time.Now()
+	manager := NewSessionManager(SessionConfig{
+		IdleTimeout: 40 * time.Second,
+		Now:         func() time.Time { return now },
+	})
+	rec := httptest.NewRecorder()
+	if _, err := manager.Login(rec, httptest.NewRequest(http.MethodPost, "/login", nil), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	// 每 15s 一次请求的活跃会话不应过期
+	for i := 0; i < 8; i++ {
+		now = now.Add(15 * time.Second)
+		if _, err := manager.Get(withCookies(httptest.NewRequest(http.MethodGet, "/", nil), rec)); err != nil {
+			t.Fatalf("request %d: expected active session, got %v", i, err)
+		}
+	}
+	now = now.Add(41 * time.Second)
+	if _, err := manager.Get(withCookies(httptest.NewRequest(http.MethodGet, "/", nil), rec)); err != ErrSessionNotFound {
+		t.Errorf("expected idle session to expire, got %v", err)
+	}
+}
+
+func TestSessionCSRFMiddleware(t *testing.T) {
+	manager := NewSessionManager(SessionConfig{})
+	rec := httptest.NewRecorder()
+	session, _ := manager.Login(rec, httptest.NewRequest(http.MethodPost, "/login", nil), "alice")
+	handler := manager.Middleware()(manager.CSRFMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s, ok := SessionFromContext(r.Context()); !ok || s.UserID != "alice" {
+			t.Error("expected session in context")
+		}
+	})))
+
+	form := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader("csrf_token="+token))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return withCookies(r, rec)
+	}
+	header := withCookies(httptest.NewRequest(http.MethodDelete, "/transfer", nil), rec)
+	header.Header.Set(DefaultCSRFHeader, session.CSRFToken)
+
+	tests := []struct {
+		name       string
+		r          *http.Request
+		wantStatus int
+	}{
+		{"safe method", withCookies(httptest.NewRequest(http.MethodGet, "/transfer", nil), rec), http.StatusOK},
+		{"form token", form(session.CSRFToken), http.StatusOK},
+		{"header token", header, http.StatusOK},
+		{"wrong token", form("forged"), http.StatusForbidden},
+		{"no session", httptest.NewRequest(http.MethodPost, "/transfer", nil), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestSessionLoginInMiddleware(t *testing.T) {
+	manager := NewSessionManager(SessionConfig{})
+	rec := httptest.NewRecorder()
+	anonymous, _ := manager.Start(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+	handler := manager.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := manager.Login(w, r, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current, err := manager.Get(r); err != nil || current.ID != session.ID {
+			t.Errorf("expected the new session in the context, got %+v %v", current, err)
+		}
+		if err := manager.Logout(w, r); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := SessionFromContext(r.Context()); ok {
+			t.Error("expected no session in the context after logout")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), withCookies(httptest.NewRequest(http.MethodPost, "/login", nil), rec))
+	if stored, _ := manager.config.Store.Get(anonymous.ID); stored != nil {
+		t.Error("expected pre-login session to be deleted")
+	}
+}
+
+func TestMemorySessionStorePrune(t *testing.T) {
+	now := time.Now()
+	store := NewMemorySessionStore()
+	NewSessionManager(SessionConfig{
+		Store:       store,
+		IdleTimeout: 30 * time.Minute,
+		Now:         func() time.Time { return now },
+	})
+	store.Save(&Session{ID: "idle", LastSeenAt: now, ExpiresAt: now.Add(24 * time.Hour)})
+	store.Save(&Session{ID: "active", LastSeenAt: now.Add(time.Hour), ExpiresAt: now.Add(24 * time.Hour)})
+
+	now = now.Add(time.Hour)
+	store.Save(&Session{ID: "new", LastSeenAt: now, ExpiresAt: now.Add(24 * time.Hour)})
+	if got, _ := store.Get("idle"); got != nil {
+		t.Error("expected idle session to be pruned")
+	}
+	if got, _ := store.Get("active"); got == nil {
+		t.Error("expected active session to be kept")
+	}
+}
+
+func TestSessionStores(t *testing.T) {
+	bolt, err := bboltdb.Open(filepath.Join(t.TempDir(), "sessions.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("open bbolt failed: %v", err)
+	}
+	defer bolt.Close()
+	badgerDir := t.TempDir()
+	badgerDB, err := badgerdb.Open(badgerDir, badger.DefaultOptions(badgerDir).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger failed: %v", err)
+	}
+	defer badgerDB.Close()
+
+	stores := map[string]SessionStore{
+		"memory": NewMemorySessionStore(),
+		"bbolt":  NewBboltSessionStore(bolt),
+		"badger": NewBadgerSessionStore(badgerDB),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			session := &Session{
+				ID:        "s1",
+				UserID:    "alice",
+				Values:    map[string]string{"k": "v"},
+				CreatedAt: time.Now(),
+				ExpiresAt: time.Now().Add(time.Hour),
+			}
+			if err := store.Save(session); err != nil {
+				t.Fatalf("save failed: %v", err)
+			}
+			got, err := store.Get("s1")
+			if err != nil || got == nil || got.UserID != "alice" || got.Values["k"] != "v" {
+				t.Fatalf("unexpected session %+v %v", got, err)
+			}
+			if err := store.Delete("s1"); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if got, err := store.Get("s1"); got != nil || err != nil {
+				t.Errorf("expected no session after delete, got %+v %v", got, err)
+			}
+		})
+	}
+
+	expired := &Session{ID: "old", ExpiresAt: time.Now().Add(-time.Minute)}
+	stores["bbolt"].Save(expired)
+	if err := stores["bbolt"].(*BboltSessionStore).DeleteExpired(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := stores["bbolt"].Get("old"); got != nil {
+		t.Error("expected expired session to be deleted")
+	}
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"time"
+
+	"github.com/vkviyu/nexus/utils/backoffutil"
+)
+
+// Backoff computes exponentially growing delays with random jitter, see backoffutil.Backoff.
+// A zero Backoff uses backoffutil.DefaultBackoff.
+type Backoff = backoffutil.Backoff
+
+// sleepContext waits for d or until done is closed, reporting whether the full delay elapsed.
+func sleepContext(done <-chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
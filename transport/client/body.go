@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RequestBody is a replayable request body set on Contract.RequestBody. Open is called for
+// every attempt and redirect, so retries never need to buffer the body.
+//
+// Builders report errors, e.g. a value that cannot be marshaled, through Do.
+type RequestBody struct {
+	// ContentType is sent unless the contract sets the Content-Type header itself.
+	ContentType string
+	// ContentLength is the body size, -1 if unknown (sent chunked).
+	ContentLength int64
+	// Open returns a new reader of the whole body.
+	Open func() (io.ReadCloser, error)
+	err  error
+}
+
+// Err returns the error of the builder, if any.
+func (b *RequestBody) Err() error {
+	return b.err
+}
+
+// BytesBody sends data as is.
+func BytesBody(contentType string, data []byte) *RequestBody {
+	return &RequestBody{
+		ContentType:   contentType,
+		ContentLength: int64(len(data)),
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+}
+
+// JSONBody sends v encoded as JSON.
+func JSONBody(v any) *RequestBody {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return &RequestBody{err: fmt.Errorf("json body: %w", err)}
+	}
+	return BytesBody("application/json", data)
+}
+
+// FormBody sends values URL-encoded.
+func FormBody(values url.Values) *RequestBody {
+	return BytesBody("application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+// StreamBody sends the readers returned by open, which is called once per attempt.
+func StreamBody(contentType string, open func() (io.ReadCloser, error)) *RequestBody {
+	return &RequestBody{ContentType: contentType, ContentLength: -1, Open: open}
+}
+
+// MultipartPart is a part of MultipartBody, see FieldPart, FilePart and ReaderPart.
+type MultipartPart struct {
+	FieldName string
+	// FileName and ContentType describe file parts; fields have an empty FileName.
+	FileName    string
+	ContentType string
+	// Open returns the content of the part, once per attempt.
+	Open func() (io.ReadCloser, error)
+}
+
+// FieldPart is a form field.
+func FieldPart(fieldName, value string) MultipartPart {
+	return MultipartPart{
+		FieldName: fieldName,
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(value)), nil
+		},
+	}
+}
+
+// FilePart is a file streamed from path, named after its base name.
+func FilePart(fieldName, path string) MultipartPart {
+	return MultipartPart{
+		FieldName:   fieldName,
+		FileName:    filepath.Base(path),
+		ContentType: "application/octet-stream",
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+}
+
+// ReaderPart is a file whose content is returned by open.
+func ReaderPart(fieldName, fileName, contentType string, open func() (io.ReadCloser, error)) MultipartPart {
+	return MultipartPart{FieldName: fieldName, FileName: fileName, ContentType: contentType, Open: open}
+}
+
+// MultipartBody sends parts as multipart/form-data. Parts are streamed while the request is
+// sent, so large files are never loaded into memory.
+func MultipartBody(parts ...MultipartPart) *RequestBody {
+	// boundary 在构造时确定，保证每次重试的 Content-Type 一致
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	return &RequestBody{
+		ContentType:   "multipart/form-data; boundary=" + boundary,
+		ContentLength: -1,
+		Open: func() (io.ReadCloser, error) {
+			pr, pw := io.Pipe()
+			go func() {
+				pw.CloseWithError(writeMultipart(pw, boundary, parts))
+			}()
+			return pr, nil
+		},
+	}
+}
+
+func writeMultipart(w io.Writer, boundary string, parts []MultipartPart) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		header := make(textproto.MIMEHeader)
+		disposition := fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(part.FieldName))
+		if part.FileName != "" {
+			disposition += fmt.Sprintf(`; filename="%s"`, escapeQuotes(part.FileName))
+		}
+		header.Set("Content-Disposition", disposition)
+		if part.ContentType != "" {
+			header.Set("Content-Type", part.ContentType)
+		}
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		content, err := part.Open()
+		if err != nil {
+			return fmt.Errorf("multipart part %q: %w", part.FieldName, err)
+		}
+		_, err = io.Copy(pw, content)
+		content.Close()
+		if err != nil {
+			return fmt.Errorf("multipart part %q: %w", part.FieldName, err)
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
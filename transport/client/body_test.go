@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			file, header, err := r.FormFile("report")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			content, _ := io.ReadAll(file)
+			io.WriteString(w, r.FormValue("title")+"|"+header.Filename+"|"+string(content))
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(path, []byte("a,b\n1,2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		body            *RequestBody
+		header          map[string]string
+		wantContentType string
+		wantBody        string
+	}{
+		{"json", JSONBody(map[string]int{"id": 1}), nil, "application/json", `{"id":1}`},
+		{"form", FormBody(url.Values{"q": {"a b"}}), nil, "application/x-www-form-urlencoded", "q=a+b"},
+		{"bytes", BytesBody("text/csv", []byte("x,y")), nil, "text/csv", "x,y"},
+		{"header override", JSONBody("hi"), map[string]string{"Content-Type": "application/vnd.api+json"}, "application/vnd.api+json", `"hi"`},
+		{"stream", StreamBody("text/plain", func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("streamed")), nil
+		}), nil, "text/plain", "streamed"},
+		{"multipart", MultipartBody(FieldPart("title", "Q1"), FilePart("report", path)), nil, "multipart/form-data; boundary=", "Q1|report.csv|a,b\n1,2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contract := NewRawContract(http.MethodPost, server.URL, tt.header, nil)
+			contract.RequestBody = tt.body
+			meta, err := Do(context.Background(), NewHTTPClient(), contract)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := meta.Headers.Get("X-Content-Type"); !strings.HasPrefix(got, tt.wantContentType) {
+				t.Errorf("expected content type %q, got %q", tt.wantContentType, got)
+			}
+			if string(meta.RawBody) != tt.wantBody {
+				t.Errorf("expected body %q, got %q (%d)", tt.wantBody, meta.RawBody, meta.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRequestBodyErrors(t *testing.T) {
+	contract := NewRawContract(http.MethodPost, "http://127.0.0.1:0", nil, nil)
+	contract.RequestBody = JSONBody(func() {})
+	if _, err := Do(context.Background(), NewHTTPClient(), contract); err == nil || !strings.Contains(err.Error(), "json body") {
+		t.Errorf("expected marshal error, got %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+	contract = NewRawContract(http.MethodPost, server.URL, nil, nil)
+	contract.RequestBody = MultipartBody(FilePart("file", filepath.Join(t.TempDir(), "missing")))
+	if _, err := Do(context.Background(), NewHTTPClient(), contract); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected missing file error, got %v", err)
+	}
+}
+
+func TestRequestBodyReplayedOnRetry(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil || r.FormValue("n") != "42" {
+			t.Errorf("attempt %d: expected multipart body, got %v", attempts.Load(), err)
+		}
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	httpClient := NewHTTPClient()
+	httpClient.Retry = &RetryPolicy{MaxAttempts: 2, Backoff: Backoff{Initial: time.Millisecond}}
+	contract := NewRawContract(http.MethodPut, server.URL, nil, nil)
+	contract.RequestBody = MultipartBody(FieldPart("n", "42"))
+	meta, err := Do(context.Background(), httpClient, contract)
+	if err != nil || meta.StatusCode != http.StatusOK || attempts.Load() != 2 {
+		t.Errorf("expected success on the second attempt, got %v %v after %d", meta, err, attempts.Load())
+	}
+}
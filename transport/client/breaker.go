@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by the *CircuitOpenError returned while a circuit is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned by Do, without sending the request, while the circuit of the
+// request is open or its half-open trial requests are in flight.
+type CircuitOpenError struct {
+	Key string
+	// Until is when the circuit becomes half-open, zero while trial requests are in flight.
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %q is open", e.Key)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through and counts consecutive failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects every request until OpenTimeout elapses.
+	CircuitOpen
+	// CircuitHalfOpen lets a few trial requests through to decide whether to close again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens a circuit,
+	// DefaultFailureThreshold if zero.
+	FailureThreshold int
+	// OpenTimeout is how long a circuit stays open before trial requests are let through,
+	// DefaultOpenTimeout if zero.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of trial requests that must succeed to close a
+	// half-open circuit, 1 if zero.
+	HalfOpenRequests int
+	// IsFailure classifies the outcome of a request, DefaultIsFailure if nil.
+	// Requests canceled by their context are never counted.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange is called, outside the lock, whenever a circuit changes state.
+	OnStateChange func(key string, from, to CircuitState)
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+var (
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+// DefaultIsFailure counts transport errors and 5xx responses as failures.
+func DefaultIsFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// CircuitBreaker keeps one circuit per key: the Contract.Name when set, otherwise the host
+// of the request URL. Set it on HTTPClient.CircuitBreaker.
+type CircuitBreaker struct {
+	config   CircuitBreakerConfig
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	// trials and successes count the requests of the half-open state.
+	trials    int
+	successes int
+	// generation changes with the state, so that outcomes of requests started in an
+	// earlier state are ignored.
+	generation uint64
+}
+
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = DefaultFailureThreshold
+	}
+	if config.OpenTimeout == 0 {
+		config.OpenTimeout = DefaultOpenTimeout
+	}
+	if config.HalfOpenRequests == 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = DefaultIsFailure
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &CircuitBreaker{
+		config:   config,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// State returns the state of the circuit of key.
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[key]
+	if !ok {
+		return CircuitClosed
+	}
+	// 打开状态超时后，下一次请求才会真正切换为半开
+	if c.state == CircuitOpen && !b.config.Now().Before(c.openedAt.Add(b.config.OpenTimeout)) {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+type stateChange struct {
+	key      string
+	from, to CircuitState
+}
+
+func (b *CircuitBreaker) notify(change *stateChange) {
+	if change != nil && b.config.OnStateChange != nil {
+		b.config.OnStateChange(change.key, change.from, change.to)
+	}
+}
+
+// setState must be called with b.mu held.
+func (b *CircuitBreaker) setState(key string, c *circuit, state CircuitState) *stateChange {
+	change := &stateChange{key: key, from: c.state, to: state}
+	c.state = state
+	c.failures, c.trials, c.successes = 0, 0, 0
+	c.generation++
+	if state == CircuitOpen {
+		c.openedAt = b.config.Now()
+	}
+	return change
+}
+
+// allow reserves a request for key, returning the function that records its outcome.
+func (b *CircuitBreaker) allow(key string) (func(resp *http.Response, err error), error) {
+	b.mu.Lock()
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	var change *stateChange
+	if c.state == CircuitOpen {
+		until := c.openedAt.Add(b.config.OpenTimeout)
+		if b.config.Now().Before(until) {
+			b.mu.Unlock()
+			return nil, &CircuitOpenError{Key: key, Until: until}
+		}
+		change = b.setState(key, c, CircuitHalfOpen)
+	}
+	if c.state == CircuitHalfOpen {
+		if c.trials >= b.config.HalfOpenRequests {
+			b.mu.Unlock()
+			b.notify(change)
+			return nil, &CircuitOpenError{Key: key}
+		}
+		c.trials++
+	}
+	generation := c.generation
+	b.mu.Unlock()
+	b.notify(change)
+
+	return func(resp *http.Response, err error) {
+		b.record(key, c, generation, resp, err)
+	}, nil
+}
+
+func (b *CircuitBreaker) record(key string, c *circuit, generation uint64, resp *http.Response, err error) {
+	if errors.Is(err, context.Canceled) {
+		// 调用方主动取消，不计入结果；半开状态释放试探名额
+		b.mu.Lock()
+		if c.generation == generation && c.state == CircuitHalfOpen {
+			c.trials--
+		}
+		b.mu.Unlock()
+		return
+	}
+	failure := b.config.IsFailure(resp, err)
+
+	b.mu.Lock()
+	var change *stateChange
+	if c.generation == generation {
+		switch c.state {
+		case CircuitClosed:
+			if !failure {
+				c.failures = 0
+			} else if c.failures++; c.failures >= b.config.FailureThreshold {
+				change = b.setState(key, c, CircuitOpen)
+			}
+		case CircuitHalfOpen:
+			if failure {
+				change = b.setState(key, c, CircuitOpen)
+			} else if c.successes++; c.successes >= b.config.HalfOpenRequests {
+				change = b.setState(key, c, CircuitClosed)
+			}
+		}
+	}
+	b.mu.Unlock()
+	b.notify(change)
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	var changes []string
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		Now:              func() time.Time { return now },
+		OnStateChange: func(key string, from, to CircuitState) {
+			changes = append(changes, from.String()+">"+to.String())
+		},
+	})
+	httpClient := NewHTTPClient()
+	httpClient.CircuitBreaker = breaker
+	call := func() error {
+		contract := NewRawContract(http.MethodGet, server.URL, nil, nil)
+		contract.Name = "users"
+		_, err := Do(context.Background(), httpClient, contract)
+		return err
+	}
+
+	for range 2 {
+		if err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if breaker.State("users") != CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", breaker.State("users"))
+	}
+	var openErr *CircuitOpenError
+	if err := call(); !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &openErr) || !openErr.Until.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected open circuit to skip the request, got %d requests", requests.Load())
+	}
+
+	// 半开：试探请求失败后重新打开，成功后关闭
+	now = now.Add(time.Minute)
+	if err := call(); err != nil || breaker.State("users") != CircuitOpen {
+		t.Fatalf("expected failed trial to reopen, got %v %s", err, breaker.State("users"))
+	}
+	now = now.Add(time.Minute)
+	status.Store(http.StatusOK)
+	if err := call(); err != nil || breaker.State("users") != CircuitClosed {
+		t.Fatalf("expected successful trial to close, got %v %s", err, breaker.State("users"))
+	}
+
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("expected changes %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("expected changes %v, got %v", want, changes)
+			break
+		}
+	}
+}
+
+func TestCircuitBreakerStopsRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	httpClient := NewHTTPClient()
+	httpClient.Retry = &RetryPolicy{MaxAttempts: 5, Backoff: Backoff{Initial: time.Millisecond}}
+	httpClient.CircuitBreaker = NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2})
+	_, err := Do(context.Background(), httpClient, NewRawContract(http.MethodGet, server.URL, nil, nil))
+	if !errors.Is(err, ErrCircuitOpen) || requests.Load() != 2 {
+		t.Errorf("expected retries to stop at the open circuit, got %v after %d requests", err, requests.Load())
+	}
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+
+	httpClient := NewHTTPClient()
+	httpClient.Limiter = NewConcurrencyLimiter(1)
+	done := make(chan error)
+	go func() {
+		_, err := Do(context.Background(), httpClient, NewRawContract(http.MethodGet, server.URL, nil, nil))
+		done <- err
+	}()
+	key := server.Listener.Addr().String()
+	for httpClient.Limiter.InFlight(key) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := Do(ctx, httpClient, NewRawContract(http.MethodGet, server.URL, nil, nil)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected second request to wait for a slot, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := httpClient.Limiter.InFlight(key); n != 0 {
+		t.Errorf("expected slot to be released after the body is closed, got %d", n)
+	}
+}
@@ -10,19 +10,43 @@ import (
 
 type (
 	Contract[T any] struct {
+		// Name identifies the contract for HTTPClient.CircuitBreaker and HTTPClient.Limiter.
+		// If empty, the host of URL is used.
+		Name string
+
 		Method  string
 		URL     string
 		Header  map[string]string
 		Body    io.Reader
 		Cookies []*http.Cookie
 
+		// PathParams fill the {name} placeholders of URL, escaped as path segments.
+		// Values are formatted like query values, see QueryValues.
+		PathParams map[string]any
+
+		// Query is added to the query string of URL, see QueryValues for the accepted sources.
+		Query any
+
+		// RequestBody is a replayable body with its Content-Type, e.g. JSONBody, FormBody or
+		// MultipartBody. It takes precedence over Body.
+		RequestBody *RequestBody
+
 		// BeforeRequest is called before sending the request.
 		// Can be used for logging, modifying request, etc.
 		BeforeRequest func(req *http.Request) error
 
 		// ParseResponse is called after receiving the response (response interceptor).
 		// Can be used for logging, custom parsing, error handling, etc.
+		// It receives responses of every status code.
 		ParseResponse func(r *http.Response) (*T, error)
+
+		// ParseError converts the *HTTPError of a non-2xx response, e.g. ErrorBody[E]().
+		// Overrides HTTPClient.DefaultParseError; unused when ParseResponse is set.
+		ParseError func(e *HTTPError) error
+
+		// Retry overrides HTTPClient.Retry for this contract.
+		// Use &RetryPolicy{MaxAttempts: 1} to disable retries.
+		Retry *RetryPolicy
 	}
 )
 
@@ -95,22 +119,6 @@ func Do[T any](ctx context.Context, h *HTTPClient, contract *Contract[T]) (*T, e
 		contract.Method = http.MethodGet
 	}
 
-	req, err := http.NewRequestWithContext(ctx, contract.Method, contract.URL, contract.Body)
-
-	if err != nil {
-		return nil, err
-	}
-
-	// 设置请求头
-	for key, val := range contract.Header {
-		req.Header.Set(key, val)
-	}
-
-	// 设置 cookie
-	for _, cookie := range contract.Cookies {
-		req.AddCookie(cookie)
-	}
-
 	// Request interceptor: Contract > HTTPClient > package default
 	beforeRequest := contract.BeforeRequest
 	if beforeRequest == nil {
@@ -119,11 +127,29 @@ func Do[T any](ctx context.Context, h *HTTPClient, contract *Contract[T]) (*T, e
 	if beforeRequest == nil {
 		beforeRequest = DefaultBeforeRequest
 	}
-	if err := beforeRequest(req); err != nil {
+
+	// Retry policy: Contract > HTTPClient
+	retry := contract.Retry
+	if retry == nil {
+		retry = h.Retry
+	}
+
+	requestURL, err := buildURL(contract.URL, contract.PathParams, contract.Query)
+	if err != nil {
 		return nil, err
 	}
 
-	resp, err := h.Client.Do(req)
+	resp, err := h.send(ctx, &request{
+		name:          contract.Name,
+		method:        contract.Method,
+		url:           requestURL,
+		header:        contract.Header,
+		body:          contract.Body,
+		requestBody:   contract.RequestBody,
+		cookies:       contract.Cookies,
+		beforeRequest: beforeRequest,
+		retry:         retry,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +161,22 @@ func Do[T any](ctx context.Context, h *HTTPClient, contract *Contract[T]) (*T, e
 		return contract.ParseResponse(resp)
 	}
 
+	// Non-2xx responses: Contract > HTTPClient error parser
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		httpErr, err := newHTTPError(resp)
+		if err != nil {
+			return nil, err
+		}
+		parseError := contract.ParseError
+		if parseError == nil {
+			parseError = h.DefaultParseError
+		}
+		if parseError == nil {
+			return nil, httpErr
+		}
+		return nil, parseError(httpErr)
+	}
+
 	// Handle raw response types: []byte and string
 	var zero T
 	switch any(zero).(type) {
@@ -157,6 +199,9 @@ func Do[T any](ctx context.Context, h *HTTPClient, contract *Contract[T]) (*T, e
 	}
 
 	result := new(T)
+	if resp.StatusCode == http.StatusNoContent {
+		return result, nil
+	}
 	if err := parseResponse(resp, result); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxErrorBodySize limits the body kept in an HTTPError.
+var MaxErrorBodySize int64 = 1 << 20
+
+// HTTPError is returned by Do for non-2xx responses when the contract has no ParseResponse.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	// Body is the raw response body, truncated to MaxErrorBodySize.
+	Body []byte
+}
+
+func (e *HTTPError) Error() string {
+	const maxLen = 200
+	body := string(e.Body)
+	if len(body) > maxLen {
+		body = body[:maxLen] + "..."
+	}
+	if body == "" {
+		return "http status " + e.Status
+	}
+	return fmt.Sprintf("http status %s: %s", e.Status, body)
+}
+
+func newHTTPError(resp *http.Response) (*HTTPError, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxErrorBodySize))
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
+}
+
+// ResponseError is the error of ErrorBody: a failed response whose body was decoded into E.
+// errors.As also finds the *HTTPError, and E or *E when it implements error.
+type ResponseError[E any] struct {
+	*HTTPError
+	Decoded E
+}
+
+func (e *ResponseError[E]) Unwrap() []error {
+	errs := []error{e.HTTPError}
+	if err, ok := any(e.Decoded).(error); ok {
+		errs = append(errs, err)
+	} else if err, ok := any(&e.Decoded).(error); ok {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// ErrorBody returns a ParseError that decodes JSON error bodies into E. Bodies that are not
+// valid JSON for E leave the *HTTPError unchanged.
+//
+// 示例：
+//
+//	contract.ParseError = client.ErrorBody[APIError]()
+//	_, err := client.Do(ctx, httpClient, contract)
+//	var apiErr *client.ResponseError[APIError]
+//	if errors.As(err, &apiErr) {
+//		fmt.Println(apiErr.StatusCode, apiErr.Decoded.Message)
+//	}
+func ErrorBody[E any]() func(e *HTTPError) error {
+	return func(e *HTTPError) error {
+		var decoded E
+		if err := json.Unmarshal(e.Body, &decoded); err != nil {
+			return e
+		}
+		return &ResponseError[E]{HTTPError: e, Decoded: decoded}
+	}
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestDoHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("<html>oops</html>"))
+		case "/json":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"not_found","message":"no such user"}`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`{"name":"alice"}`))
+		}
+	}))
+	defer server.Close()
+	httpClient := NewHTTPClient()
+	ctx := context.Background()
+
+	if u, err := Do(ctx, httpClient, &Contract[user]{URL: server.URL + "/ok"}); err != nil || u.Name != "alice" {
+		t.Fatalf("expected alice, got %v %v", u, err)
+	}
+	if u, err := Do(ctx, httpClient, &Contract[user]{URL: server.URL + "/empty"}); err != nil || u == nil {
+		t.Fatalf("expected empty result for 204, got %v %v", u, err)
+	}
+
+	_, err := Do(ctx, httpClient, &Contract[user]{URL: server.URL + "/html"})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusInternalServerError || string(httpErr.Body) != "<html>oops</html>" {
+		t.Fatalf("expected *HTTPError with raw body, got %v", err)
+	}
+	if _, err := Do(ctx, httpClient, &Contract[string]{URL: server.URL + "/html"}); !errors.As(err, &httpErr) {
+		t.Errorf("expected *HTTPError for string contracts, got %v", err)
+	}
+
+	_, err = Do(ctx, httpClient, &Contract[user]{URL: server.URL + "/json", ParseError: ErrorBody[apiError]()})
+	var responseErr *ResponseError[apiError]
+	if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusNotFound || responseErr.Decoded.Code != "not_found" {
+		t.Fatalf("expected decoded error body, got %v", err)
+	}
+	var decoded *apiError
+	if !errors.As(err, &decoded) || decoded.Message != "no such user" || !errors.As(err, &httpErr) {
+		t.Errorf("expected errors.As to reach the decoded body and the HTTPError, got %v", err)
+	}
+
+	httpClient.DefaultParseError = ErrorBody[apiError]()
+	_, err = Do(ctx, httpClient, &Contract[user]{URL: server.URL + "/html"})
+	if errors.As(err, &responseErr) || !errors.As(err, &httpErr) {
+		t.Errorf("expected undecodable body to keep the *HTTPError, got %T", err)
+	}
+}
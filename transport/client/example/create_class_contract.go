@@ -1,13 +1,7 @@
 // Code generated by nexus genutil. DO NOT EDIT.
 package example
 
-import (
-	"bytes"
-	"encoding/json"
-	"io"
-
-	"github.com/vkviyu/nexus/transport/client"
-)
+import "github.com/vkviyu/nexus/transport/client"
 
 type (
 	ClassParams struct {
@@ -57,15 +51,12 @@ type (
 )
 
 // CreateClass Create a new class in the specified school
-var CreateClass = func(schoolId string, query *CreateClassQuery, body *CreateClassBody) *client.Contract[CreateClassResponse] {
+var CreateClass = func(params *ClassParams, body *CreateClassBody) *client.Contract[CreateClassResponse] {
 	return &client.Contract[CreateClassResponse]{
-		URL:    "https://api.example.com" + "/classes/" + schoolId,
-		Method: "POST",
-		Body:   toJSONReader(body),
+		URL:         "https://api.example.com" + "/classes/{schoolId}",
+		PathParams:  map[string]any{"schoolId": params.SchoolID},
+		Query:       map[string]any{"semester": params.Semester, "year": params.Year},
+		Method:      "POST",
+		RequestBody: client.JSONBody(body),
 	}
 }
-
-func toJSONReader(v any) io.Reader {
-	data, _ := json.Marshal(v)
-	return bytes.NewReader(data)
-}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 )
 
@@ -19,6 +20,22 @@ type HTTPClient struct {
 	// Called after receiving response if Contract.ParseResponse is nil.
 	// If nil, falls back to package-level DefaultParseResponse (JSON).
 	DefaultParseResponse func(r *http.Response, target any) error
+
+	// DefaultParseError converts the *HTTPError of non-2xx responses if Contract.ParseError is nil.
+	// If nil, the *HTTPError is returned as is.
+	DefaultParseError func(e *HTTPError) error
+
+	// Retry is the default retry policy for this client, overridden by Contract.Retry.
+	// If nil, requests are sent once.
+	Retry *RetryPolicy
+
+	// CircuitBreaker and Limiter are optional; see NewCircuitBreaker and NewConcurrencyLimiter.
+	// They apply to every attempt, so retries are also rejected by an open circuit.
+	CircuitBreaker *CircuitBreaker
+	Limiter        *ConcurrencyLimiter
+
+	// Middlewares wrap every attempt in addition to the request and response hooks, see Use.
+	Middlewares []Middleware
 }
 
 func NewHTTPClient() *HTTPClient {
@@ -72,6 +89,11 @@ func NewProxyHTTPClientFrom(base *HTTPClient, proxyURL *url.URL) *HTTPClient {
 		Client:               &http.Client{Transport: newTransport},
 		DefaultBeforeRequest: base.DefaultBeforeRequest,
 		DefaultParseResponse: base.DefaultParseResponse,
+		DefaultParseError:    base.DefaultParseError,
+		Retry:                base.Retry,
+		CircuitBreaker:       base.CircuitBreaker,
+		Limiter:              base.Limiter,
+		Middlewares:          slices.Clone(base.Middlewares),
 	}
 }
 
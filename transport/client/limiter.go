@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// ConcurrencyLimiter bounds the requests in flight per key, keyed like CircuitBreaker.
+// A request holds its slot until its response body is closed; requests over the limit wait
+// for a free slot or for their context to be done. Set it on HTTPClient.Limiter.
+type ConcurrencyLimiter struct {
+	maxInFlight int
+	mu          sync.Mutex
+	slots       map[string]chan struct{}
+}
+
+func NewConcurrencyLimiter(maxInFlight int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		maxInFlight: max(maxInFlight, 1),
+		slots:       make(map[string]chan struct{}),
+	}
+}
+
+func (l *ConcurrencyLimiter) slotsOf(key string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots, ok := l.slots[key]
+	if !ok {
+		slots = make(chan struct{}, l.maxInFlight)
+		l.slots[key] = slots
+	}
+	return slots
+}
+
+// InFlight returns the number of requests in flight for key.
+func (l *ConcurrencyLimiter) InFlight(key string) int {
+	return len(l.slotsOf(key))
+}
+
+// acquire waits for a slot of key and returns the function releasing it.
+func (l *ConcurrencyLimiter) acquire(ctx context.Context, key string) (func(), error) {
+	slots := l.slotsOf(key)
+	select {
+	case slots <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() { <-slots })
+		}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releaseOnClose calls release when the body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
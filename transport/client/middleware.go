@@ -0,0 +1,196 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoundTripFunc sends a request and returns its response.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the sending of a request. Unlike the BeforeRequest and ParseResponse hooks,
+// which override each other, every middleware of HTTPClient.Middlewares runs for every
+// attempt of every contract, after the BeforeRequest hook.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Use appends middlewares to the client. The first middleware is the outermost.
+func (c *HTTPClient) Use(middlewares ...Middleware) {
+	c.Middlewares = append(c.Middlewares, middlewares...)
+}
+
+func (c *HTTPClient) chain() RoundTripFunc {
+	next := RoundTripFunc(c.Client.Do)
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		next = c.Middlewares[i](next)
+	}
+	return next
+}
+
+// RequestHook adapts a request hook with the BeforeRequest signature, such as
+// auth.HMACSigner.Sign, to a Middleware.
+func RequestHook(hook func(req *http.Request) error) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if err := hook(req); err != nil {
+				return nil, err
+			}
+			return next(req)
+		}
+	}
+}
+
+// AuthHeader sets header to the value returned by valueFunc for every request, e.g. a token
+// that is refreshed in the background.
+func AuthHeader(header string, valueFunc func(ctx context.Context) (string, error)) Middleware {
+	return RequestHook(func(req *http.Request) error {
+		value, err := valueFunc(req.Context())
+		if err != nil {
+			return err
+		}
+		req.Header.Set(header, value)
+		return nil
+	})
+}
+
+// BearerToken sets the Authorization header to "Bearer <token>", see AuthHeader.
+func BearerToken(tokenFunc func(ctx context.Context) (string, error)) Middleware {
+	return AuthHeader("Authorization", func(ctx context.Context) (string, error) {
+		token, err := tokenFunc(ctx)
+		return "Bearer " + token, err
+	})
+}
+
+// RequestIDHeader is the header set by the RequestID middleware.
+var RequestIDHeader = "X-Request-ID"
+
+// RequestID sets RequestIDHeader unless the request already has it. The ID is taken from
+// idFunc, e.g. handler.RequestIDFromContext to propagate the ID of an incoming request,
+// or is a new UUID if idFunc is nil or returns "".
+func RequestID(idFunc func(ctx context.Context) string) Middleware {
+	return RequestHook(func(req *http.Request) error {
+		if req.Header.Get(RequestIDHeader) != "" {
+			return nil
+		}
+		var id string
+		if idFunc != nil {
+			id = idFunc(req.Context())
+		}
+		if id == "" {
+			id = uuid.NewString()
+		}
+		req.Header.Set(RequestIDHeader, id)
+		return nil
+	})
+}
+
+// Logging logs every request and its outcome with logFn, e.g. log.Printf.
+func Logging(logFn func(format string, args ...any)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			logFn("→ %s %s", req.Method, req.URL)
+			start := time.Now()
+			resp, err := next(req)
+			if err != nil {
+				logFn("← %s %s failed after %s: %v", req.Method, req.URL, time.Since(start), err)
+			} else {
+				logFn("← %s %s %d (%s)", req.Method, req.URL, resp.StatusCode, time.Since(start))
+			}
+			return resp, err
+		}
+	}
+}
+
+// RequestMetrics describes a completed request, see Metrics.
+type RequestMetrics struct {
+	Method string
+	Host   string
+	Path   string
+	// StatusCode is 0 when Err is set.
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// Metrics reports every request to observe, e.g. to update latency histograms.
+// Duration is measured until the response headers are received.
+func Metrics(observe func(m RequestMetrics)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			m := RequestMetrics{
+				Method:   req.Method,
+				Host:     req.URL.Host,
+				Path:     req.URL.Path,
+				Duration: time.Since(start),
+				Err:      err,
+			}
+			if resp != nil {
+				m.StatusCode = resp.StatusCode
+			}
+			observe(m)
+			return resp, err
+		}
+	}
+}
+
+// CapturedExchange holds the bodies captured by CaptureBody, truncated to its limit.
+type CapturedExchange struct {
+	Request      *http.Request
+	RequestBody  []byte
+	Response     *http.Response
+	ResponseBody []byte
+	Err          error
+}
+
+// CaptureBody passes the request and response bodies, up to maxSize bytes each, to fn,
+// e.g. for debugging or auditing. The response body stays fully readable by the caller.
+func CaptureBody(maxSize int64, fn func(exchange *CapturedExchange)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			exchange := &CapturedExchange{Request: req}
+			if req.GetBody != nil && req.Body != nil && req.Body != http.NoBody {
+				if body, err := req.GetBody(); err == nil {
+					exchange.RequestBody, _ = io.ReadAll(io.LimitReader(body, maxSize))
+					body.Close()
+				}
+			}
+			resp, err := next(req)
+			exchange.Response, exchange.Err = resp, err
+			if resp != nil {
+				head, readErr := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+				exchange.ResponseBody = head
+				// 已读取的部分与剩余部分拼接，调用方仍可读取完整响应体
+				resp.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(head), errReader(readErr, resp.Body)), Closer: resp.Body}
+			}
+			fn(exchange)
+			return resp, err
+		}
+	}
+}
+
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader returns r, or a reader failing with err if err is set.
+func errReader(err error, r io.Reader) io.Reader {
+	if err != nil {
+		return &failingReader{err: err}
+	}
+	return r
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
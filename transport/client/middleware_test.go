@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPClientMiddlewares(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Auth", r.Header.Get("Authorization"))
+		w.Header().Set("X-Seen-Request-ID", r.Header.Get(RequestIDHeader))
+		w.Header().Set("X-Seen-Hook", r.Header.Get("X-Hook"))
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next(req)
+			}
+		}
+	}
+	var metrics []RequestMetrics
+	var captured *CapturedExchange
+	var logs []string
+
+	httpClient := NewHTTPClient()
+	httpClient.DefaultBeforeRequest = func(req *http.Request) error {
+		req.Header.Set("Authorization", "overridden by the contract hook")
+		return nil
+	}
+	httpClient.Use(
+		tag("outer"),
+		BearerToken(func(ctx context.Context) (string, error) { return "secret", nil }),
+		RequestID(func(ctx context.Context) string { return "req-1" }),
+		Logging(func(format string, args ...any) { logs = append(logs, format) }),
+		Metrics(func(m RequestMetrics) { metrics = append(metrics, m) }),
+		CaptureBody(3, func(exchange *CapturedExchange) { captured = exchange }),
+		tag("inner"),
+	)
+
+	contract := NewRawContract(http.MethodPost, server.URL+"/echo", nil, strings.NewReader("hello"))
+	contract.BeforeRequest = func(req *http.Request) error {
+		req.Header.Set("X-Hook", "contract")
+		return nil
+	}
+	meta, err := Do(context.Background(), httpClient, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := meta.Headers.Get("X-Seen-Hook"); got != "contract" {
+		t.Errorf("expected contract hook to run, got %q", got)
+	}
+	if got := meta.Headers.Get("X-Seen-Auth"); got != "Bearer secret" {
+		t.Errorf("expected middleware auth header, got %q", got)
+	}
+	if got := meta.Headers.Get("X-Seen-Request-ID"); got != "req-1" {
+		t.Errorf("expected request ID, got %q", got)
+	}
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("expected outer before inner, got %v", order)
+	}
+	if len(logs) != 2 || len(metrics) != 1 || metrics[0].StatusCode != http.StatusOK || metrics[0].Path != "/echo" {
+		t.Errorf("unexpected logs %v or metrics %+v", logs, metrics)
+	}
+	if captured == nil || string(captured.RequestBody) != "hel" || string(captured.ResponseBody) != "hel" {
+		t.Fatalf("expected truncated bodies, got %+v", captured)
+	}
+	if string(meta.RawBody) != "hello" {
+		t.Errorf("expected full response body after capture, got %q", meta.RawBody)
+	}
+}
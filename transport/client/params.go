@@ -0,0 +1,246 @@
+package client
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{([^{}/?#]+)\}`)
+
+// buildURL fills the {name} placeholders of rawURL with the escaped pathParams and adds the
+// parameters of query to its query string. A path parameter that is nil or formats to ""
+// is an error, it would leave an empty path segment.
+func buildURL(rawURL string, pathParams map[string]any, query any) (string, error) {
+	if len(pathParams) == 0 && query == nil {
+		return rawURL, nil
+	}
+	if pathParams != nil {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(rawURL, -1) {
+			if _, ok := pathParams[match[1]]; !ok {
+				return "", fmt.Errorf("client: missing path parameter %q", match[1])
+			}
+		}
+	}
+	for name, value := range pathParams {
+		s, err := formatParam(reflect.ValueOf(value))
+		if err != nil {
+			return "", fmt.Errorf("client: path parameter %q: %w", name, err)
+		}
+		if s == "" {
+			return "", fmt.Errorf("client: empty path parameter %q", name)
+		}
+		rawURL = strings.ReplaceAll(rawURL, "{"+name+"}", url.PathEscape(s))
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if query == nil {
+		return u.String(), nil
+	}
+	values, err := QueryValues(query)
+	if err != nil {
+		return "", err
+	}
+	if len(values) > 0 {
+		merged := u.Query()
+		for key, vs := range values {
+			merged[key] = append(merged[key], vs...)
+		}
+		u.RawQuery = merged.Encode()
+	}
+	return u.String(), nil
+}
+
+// QueryValues converts the query source of a Contract to url.Values. Supported sources are
+// url.Values, map[string]string, map[string][]string, map[string]any and structs or struct
+// pointers. A nil pointer yields no values.
+//
+// Struct fields are named by their url tag, else their json tag, else the field name; "-"
+// skips a field and anonymous struct fields are flattened. Tag options:
+//   - omitempty skips zero values
+//   - comma encodes slices as one comma separated value instead of repeating the key
+//
+// Values may be strings, booleans, numbers, time.Time (RFC 3339), encoding.TextMarshaler
+// or fmt.Stringer implementations, pointers to them and slices of them. Nil pointers are
+// skipped.
+func QueryValues(query any) (url.Values, error) {
+	switch q := query.(type) {
+	case nil:
+		return nil, nil
+	case url.Values:
+		return q, nil
+	case map[string][]string:
+		return url.Values(q), nil
+	case map[string]string:
+		values := make(url.Values, len(q))
+		for k, v := range q {
+			values.Set(k, v)
+		}
+		return values, nil
+	}
+
+	v := reflect.ValueOf(query)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	values := make(url.Values)
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("client: unsupported query map key type %s", v.Type().Key())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := addQueryValue(values, iter.Key().String(), iter.Value(), false, false); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Struct:
+		if err := addStructQuery(values, v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("client: unsupported query type %T", query)
+	}
+	return values, nil
+}
+
+func addStructQuery(values url.Values, v reflect.Value) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		// 与 encoding/json 一致，未导出的嵌入结构体字段仍会展开
+		if !field.IsExported() && !(field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct) {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("url")
+		if !ok {
+			tag = field.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if field.Anonymous && name == "" {
+			for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := addStructQuery(values, fv); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty, comma := false, false
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				omitEmpty = true
+			case "comma":
+				comma = true
+			}
+		}
+		if err := addQueryValue(values, name, fv, omitEmpty, comma); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addQueryValue(values url.Values, name string, v reflect.Value, omitEmpty, comma bool) error {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || (omitEmpty && v.IsZero()) {
+		return nil
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		items := make([]string, 0, v.Len())
+		for i := range v.Len() {
+			s, err := formatParam(v.Index(i))
+			if err != nil {
+				return fmt.Errorf("client: query parameter %q: %w", name, err)
+			}
+			items = append(items, s)
+		}
+		switch {
+		case len(items) == 0:
+		case comma:
+			values.Add(name, strings.Join(items, ","))
+		default:
+			values[name] = append(values[name], items...)
+		}
+		return nil
+	}
+	s, err := formatParam(v)
+	if err != nil {
+		return fmt.Errorf("client: query parameter %q: %w", name, err)
+	}
+	values.Add(name, s)
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// formatParam formats a scalar path or query parameter.
+func formatParam(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", nil
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+	if v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			text, err := x.MarshalText()
+			return string(text), err
+		case fmt.Stringer:
+			return x.String(), nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
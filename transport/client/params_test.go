@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type listQuery struct {
+	Page     int       `url:"page"`
+	Keyword  string    `json:"keyword,omitempty"`
+	Tags     []string  `url:"tag"`
+	Fields   []string  `url:"fields,comma"`
+	Since    time.Time `url:"since,omitempty"`
+	Limit    *int      `url:"limit"`
+	Internal string    `url:"-"`
+	pagingQuery
+}
+
+type pagingQuery struct {
+	Sort string `url:"sort,omitempty"`
+}
+
+func TestQueryValues(t *testing.T) {
+	limit := 10
+	tests := []struct {
+		name  string
+		query any
+		want  string
+	}{
+		{"nil", nil, ""},
+		{"values", url.Values{"a": {"1", "2"}}, "a=1&a=2"},
+		{"string map", map[string]string{"a": "x y"}, "a=x+y"},
+		{"any map", map[string]any{"n": 3, "ok": true, "ids": []int{1, 2}}, "ids=1&ids=2&n=3&ok=true"},
+		{"struct", listQuery{
+			Page:     2,
+			Tags:     []string{"go", "http"},
+			Fields:   []string{"id", "name"},
+			Since:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Limit:    &limit,
+			Internal: "secret",
+			pagingQuery: pagingQuery{
+				Sort: "name",
+			},
+		}, "fields=id%2Cname&limit=10&page=2&since=2024-01-02T03%3A04%3A05Z&sort=name&tag=go&tag=http"},
+		{"omitempty", &listQuery{Keyword: "q"}, "keyword=q&page=0"},
+		{"nil pointer", (*listQuery)(nil), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := QueryValues(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := values.Encode(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := QueryValues(42); err == nil {
+		t.Error("expected error for unsupported query type")
+	}
+	if _, err := QueryValues(map[string]any{"f": func() {}}); err == nil {
+		t.Error("expected error for unsupported value type")
+	}
+}
+
+func TestBuildURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		pathParams map[string]any
+		query      any
+		want       string
+		wantErr    string
+	}{
+		{"unchanged", "http://api/users/{id}", nil, nil, "http://api/users/{id}", ""},
+		{"path", "http://api/schools/{school}/users/{id}", map[string]any{"school": 7, "id": "a/b c"}, nil, "http://api/schools/7/users/a%2Fb%20c", ""},
+		{"query merged", "http://api/users?page=1", nil, map[string]string{"q": "x"}, "http://api/users?page=1&q=x", ""},
+		{"missing", "http://api/users/{id}/{tab}", map[string]any{"id": 1}, nil, "", `missing path parameter "tab"`},
+		{"empty", "http://api/users/{id}/posts", map[string]any{"id": ""}, nil, "", `empty path parameter "id"`},
+		{"nil", "http://api/users/{id}/posts", map[string]any{"id": nil}, nil, "", `empty path parameter "id"`},
+		{"nil pointer", "http://api/users/{id}/posts", map[string]any{"id": (*int)(nil)}, nil, "", `empty path parameter "id"`},
+		{"bad query", "http://api/users", nil, 1, "", "unsupported query type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildURL(tt.url, tt.pathParams, tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestContractParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.EscapedPath() + "?" + r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	contract := NewRawContract(http.MethodGet, server.URL+"/users/{id}", nil, nil)
+	contract.PathParams = map[string]any{"id": "a b"}
+	contract.Query = listQuery{Page: 3, Tags: []string{"x"}}
+	meta, err := Do(context.Background(), NewHTTPClient(), contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/users/a%20b?page=3&tag=x"; string(meta.RawBody) != want {
+		t.Errorf("expected %q, got %q", want, meta.RawBody)
+	}
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// request describes an outgoing request independently of the response type of the contract.
+type request struct {
+	name          string
+	method        string
+	url           string
+	header        map[string]string
+	body          io.Reader
+	requestBody   *RequestBody
+	cookies       []*http.Cookie
+	beforeRequest func(req *http.Request) error
+	retry         *RetryPolicy
+}
+
+// newHTTPRequest builds the request of one attempt, running the request interceptor.
+// The body is reopened from requestBody when set, otherwise the one-shot body is used.
+func (r *request) newHTTPRequest(ctx context.Context) (*http.Request, error) {
+	body := r.body
+	if r.requestBody != nil {
+		opened, err := r.requestBody.Open()
+		if err != nil {
+			return nil, err
+		}
+		body = opened
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, body)
+	if err != nil {
+		if closer, ok := body.(io.Closer); ok && r.requestBody != nil {
+			closer.Close()
+		}
+		return nil, err
+	}
+	if r.requestBody != nil {
+		// GetBody 使重定向（307/308）也能重放请求体
+		req.GetBody = r.requestBody.Open
+		req.ContentLength = r.requestBody.ContentLength
+		if r.requestBody.ContentType != "" {
+			req.Header.Set("Content-Type", r.requestBody.ContentType)
+		}
+	}
+
+	// 设置请求头
+	for key, val := range r.header {
+		req.Header.Set(key, val)
+	}
+
+	// 设置 cookie
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+
+	if err := r.beforeRequest(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// send sends r, retrying it according to its retry policy.
+func (h *HTTPClient) send(ctx context.Context, r *request) (*http.Response, error) {
+	if r.requestBody != nil && r.requestBody.err != nil {
+		return nil, r.requestBody.err
+	}
+	if !r.retry.enabled() {
+		req, err := r.newHTTPRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return h.roundTrip(r, req)
+	}
+
+	// Body 是一次性的 io.Reader，重试前先缓存，每次尝试重新构造
+	if r.requestBody == nil && r.body != nil {
+		data, err := io.ReadAll(r.body)
+		if err != nil {
+			return nil, err
+		}
+		r.requestBody = BytesBody("", data)
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := r.newHTTPRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := h.roundTrip(r, req)
+		if errors.Is(err, ErrCircuitOpen) {
+			return nil, err
+		}
+		if attempt+1 >= r.retry.MaxAttempts || ctx.Err() != nil || !r.retry.allows(req) {
+			return resp, err
+		}
+		delay, retry := r.retry.retryDelay(attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			// 读完并关闭响应体，以便复用连接
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+		if !sleepContext(ctx.Done(), delay) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// key identifies the circuit and the concurrency limit of r.
+func (r *request) key(req *http.Request) string {
+	if r.name != "" {
+		return r.name
+	}
+	return req.URL.Host
+}
+
+// roundTrip sends one attempt through the limiter, the circuit breaker and the middlewares
+// of the client.
+func (h *HTTPClient) roundTrip(r *request, req *http.Request) (*http.Response, error) {
+	key := r.key(req)
+	var release func()
+	if h.Limiter != nil {
+		var err error
+		if release, err = h.Limiter.acquire(req.Context(), key); err != nil {
+			return nil, err
+		}
+	}
+	var record func(resp *http.Response, err error)
+	if h.CircuitBreaker != nil {
+		var err error
+		if record, err = h.CircuitBreaker.allow(key); err != nil {
+			if release != nil {
+				release()
+			}
+			return nil, err
+		}
+	}
+
+	resp, err := h.chain()(req)
+	if record != nil {
+		record(resp, err)
+	}
+	if release != nil {
+		if err != nil {
+			release()
+		} else {
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+		}
+	}
+	return resp, err
+}
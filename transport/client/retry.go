@@ -0,0 +1,114 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how Do retries a failed request.
+//
+// A request is retried when the transport fails with a retryable error or the response has a
+// retryable status code. Non-idempotent methods (POST, PATCH, CONNECT) are only retried when
+// the request carries an Idempotency-Key header or RetryNonIdempotent is set. The body of the
+// contract is buffered so that every attempt sends it again, and BeforeRequest runs again
+// for every attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first; values below 2
+	// disable retries.
+	MaxAttempts int
+	// Backoff computes the delay between attempts, backoffutil.DefaultBackoff if zero.
+	Backoff Backoff
+	// RetryableStatus lists the retried status codes, DefaultRetryableStatus if nil.
+	RetryableStatus []int
+	// RetryableError reports whether a transport error is retried, DefaultRetryableError if nil.
+	RetryableError func(err error) bool
+	// RetryNonIdempotent retries every method.
+	RetryNonIdempotent bool
+	// MaxRetryAfter caps the delay requested by a Retry-After header, DefaultMaxRetryAfter
+	// if zero. Responses asking for a longer delay are returned without retrying.
+	MaxRetryAfter time.Duration
+}
+
+var (
+	DefaultRetryableStatus = []int{
+		http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+	DefaultMaxRetryAfter = time.Minute
+)
+
+// IdempotencyKeyHeader marks a non-idempotent request as safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultRetryableError retries connection resets and refusals, unexpected EOFs and
+// timeouts of a single attempt.
+func DefaultRetryableError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (p *RetryPolicy) enabled() bool {
+	return p != nil && p.MaxAttempts > 1
+}
+
+// allows reports whether req may be sent more than once.
+func (p *RetryPolicy) allows(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return p.RetryNonIdempotent || req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// retryDelay reports whether the outcome of attempt is retried and after which delay.
+func (p *RetryPolicy) retryDelay(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		retryable := p.RetryableError
+		if retryable == nil {
+			retryable = DefaultRetryableError
+		}
+		return p.Backoff.Delay(attempt), retryable(err)
+	}
+	statuses := p.RetryableStatus
+	if statuses == nil {
+		statuses = DefaultRetryableStatus
+	}
+	if !slices.Contains(statuses, resp.StatusCode) {
+		return 0, false
+	}
+	if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		maxDelay := p.MaxRetryAfter
+		if maxDelay == 0 {
+			maxDelay = DefaultMaxRetryAfter
+		}
+		return delay, delay <= maxDelay
+	}
+	return p.Backoff.Delay(attempt), true
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
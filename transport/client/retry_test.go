@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoRetry(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, Backoff: Backoff{Initial: time.Millisecond}}
+	tests := []struct {
+		name         string
+		method       string
+		header       map[string]string
+		statuses     []int
+		retryAfter   string
+		wantAttempts int32
+		wantStatus   int
+	}{
+		{"recovers", http.MethodPost, map[string]string{IdempotencyKeyHeader: "k1"}, []int{503, 502, 200}, "", 3, 200},
+		{"gives up", http.MethodGet, nil, []int{503, 503, 503, 200}, "", 3, 503},
+		{"not retryable status", http.MethodGet, nil, []int{500, 200}, "", 1, 500},
+		{"non-idempotent", http.MethodPost, nil, []int{503, 200}, "", 1, 503},
+		{"retry after", http.MethodPut, nil, []int{429, 200}, "0", 2, 200},
+		{"retry after too long", http.MethodGet, nil, []int{429, 200}, "3600", 1, 429},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				if body, _ := io.ReadAll(r.Body); string(body) != "payload" {
+					t.Errorf("attempt %d: expected body to be resent, got %q", n, body)
+				}
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer server.Close()
+
+			httpClient := NewHTTPClient()
+			httpClient.Retry = policy
+			meta, err := Do(context.Background(), httpClient, NewRawContract(tt.method, server.URL, tt.header, strings.NewReader("payload")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts.Load() != tt.wantAttempts || meta.StatusCode != tt.wantStatus {
+				t.Errorf("expected %d attempts ending with %d, got %d ending with %d", tt.wantAttempts, tt.wantStatus, attempts.Load(), meta.StatusCode)
+			}
+		})
+	}
+}
+
+func TestDoRetryContractOverride(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	httpClient := NewHTTPClient()
+	httpClient.Retry = &RetryPolicy{MaxAttempts: 3, Backoff: Backoff{Initial: time.Millisecond}}
+	contract := NewRawContract(http.MethodGet, server.URL, nil, nil)
+	contract.Retry = &RetryPolicy{MaxAttempts: 1}
+	if _, err := Do(context.Background(), httpClient, contract); err != nil {
+		t.Fatal(err)
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("expected contract policy to disable retries, got %d attempts", attempts.Load())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	contract.Retry = &RetryPolicy{MaxAttempts: 5, Backoff: Backoff{Initial: time.Hour}}
+	if _, err := Do(ctx, httpClient, contract); err != context.DeadlineExceeded {
+		t.Errorf("expected backoff to stop with the context, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("2"); !ok || d != 2*time.Second {
+		t.Errorf("expected 2s, got %v %v", d, ok)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d < 59*time.Minute {
+		t.Errorf("expected about 1h, got %v %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("expected invalid value to be ignored")
+	}
+}
@@ -1,10 +1,17 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/vkviyu/nexus/utils/backoffutil"
 )
 
 type WebSocketClient struct {
@@ -12,6 +19,9 @@ type WebSocketClient struct {
 }
 
 // GetWebSocketConn creates a new WebSocket connection to the given host and path.
+//
+// Deprecated: GetWebSocketConn dials once over ws:// only. Use NewReconnectingWebSocketClient,
+// which supports wss://, custom dialers and automatic reconnection.
 func GetWebSocketConn(host, path string, header http.Header) (*WebSocketClient, *http.Response, error) {
 	u := url.URL{Scheme: "ws", Host: host, Path: path}
 	wsConn, resp, err := websocket.DefaultDialer.Dial(u.String(), header)
@@ -20,3 +30,434 @@ func GetWebSocketConn(host, path string, header http.Header) (*WebSocketClient,
 	}
 	return &WebSocketClient{wsConn}, resp, nil
 }
+
+// ConnState is the connection state of a ReconnectingWebSocketClient.
+type ConnState int
+
+const (
+	StateDisconnected ConnState = iota
+	StateConnecting
+	StateConnected
+	StateReconnecting
+	StateClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrNotConnected is returned by Send when the client has no live connection.
+	ErrNotConnected = errors.New("websocket client not connected")
+	// ErrClientClosed is returned when using a closed ReconnectingWebSocketClient.
+	ErrClientClosed = errors.New("websocket client closed")
+	// ErrClientStarted is returned by Connect when the client is already connected.
+	ErrClientStarted = errors.New("websocket client already started")
+)
+
+// DefaultWebSocketWriteTimeout bounds the replay of the subscriptions after a connection is
+// established, see ReconnectingWebSocketClient.WriteTimeout.
+var DefaultWebSocketWriteTimeout = 10 * time.Second
+
+type wsFrame struct {
+	messageType int
+	data        []byte
+}
+
+// ReconnectingWebSocketClient is a WebSocket client that reconnects automatically.
+//
+// After every reconnection, the messages registered with Subscribe are sent again in
+// registration order, so server-side subscriptions and handshakes survive connection loss.
+//
+// Example:
+//
+//	c := client.NewReconnectingWebSocketClient("wss://example.com/ws",
+//		client.WithOnMessage(func(messageType int, data []byte) {
+//			fmt.Printf("received: %s\n", data)
+//		}),
+//		client.WithOnStateChange(func(from, to client.ConnState) {
+//			log.Printf("websocket %s -> %s", from, to)
+//		}),
+//	)
+//	if err := c.Connect(ctx); err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//	c.Subscribe(websocket.TextMessage, []byte(`{"type":"subscribe","topic":"prices"}`))
+type ReconnectingWebSocketClient struct {
+	URL    string
+	Header http.Header
+	Dialer *websocket.Dialer
+
+	// Backoff controls the delay between reconnection attempts.
+	Backoff Backoff
+	// MaxReconnectAttempts limits consecutive failed reconnections, 0 means unlimited.
+	MaxReconnectAttempts int
+
+	// HeartbeatInterval is the ping interval, 0 disables heartbeats.
+	// A connection without a pong for HeartbeatInterval+PongTimeout is considered dead.
+	HeartbeatInterval time.Duration
+	PongTimeout       time.Duration
+
+	// WriteTimeout bounds the replay of the subscriptions on a new connection,
+	// DefaultWebSocketWriteTimeout if zero.
+	WriteTimeout time.Duration
+
+	OnMessage     func(messageType int, data []byte)
+	OnStateChange func(from, to ConnState)
+	OnError       func(err error)
+
+	mu            sync.Mutex
+	conn          *websocket.Conn
+	state         ConnState
+	subscriptions []wsFrame
+	cancel        context.CancelFunc
+	closed        bool
+	writeMu       sync.Mutex
+}
+
+type ReconnectingWebSocketOption func(*ReconnectingWebSocketClient)
+
+func WithHeader(header http.Header) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		c.Header = header
+	}
+}
+
+func WithDialer(dialer *websocket.Dialer) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		c.Dialer = dialer
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for wss:// URLs.
+func WithTLSConfig(tlsConfig *tls.Config) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		dialer := *websocket.DefaultDialer
+		if c.Dialer != nil {
+			dialer = *c.Dialer
+		}
+		dialer.TLSClientConfig = tlsConfig
+		c.Dialer = &dialer
+	}
+}
+
+func WithBackoff(backoff Backoff) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		c.Backoff = backoff
+	}
+}
+
+func WithMaxReconnectAttempts(attempts int) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		c.MaxReconnectAttempts = attempts
+	}
+}
+
+func WithHeartbeat(interval, pongTimeout time.Duration) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		c.HeartbeatInterval = interval
+		c.PongTimeout = pongTimeout
+	}
+}
+
+func WithOnMessage(onMessage func(messageType int, data []byte)) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		c.OnMessage = onMessage
+	}
+}
+
+func WithOnStateChange(onStateChange func(from, to ConnState)) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		c.OnStateChange = onStateChange
+	}
+}
+
+func WithOnError(onError func(err error)) ReconnectingWebSocketOption {
+	return func(c *ReconnectingWebSocketClient) {
+		c.OnError = onError
+	}
+}
+
+// NewReconnectingWebSocketClient creates a client for rawURL (ws:// or wss://). Call Connect to start it.
+func NewReconnectingWebSocketClient(rawURL string, options ...ReconnectingWebSocketOption) *ReconnectingWebSocketClient {
+	c := &ReconnectingWebSocketClient{
+		URL:     rawURL,
+		Backoff: backoffutil.DefaultBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.Dialer == nil {
+		c.Dialer = websocket.DefaultDialer
+	}
+	if c.HeartbeatInterval > 0 && c.PongTimeout <= 0 {
+		c.PongTimeout = c.HeartbeatInterval
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = DefaultWebSocketWriteTimeout
+	}
+	return c
+}
+
+// Connect dials the server and keeps the connection alive in the background until ctx
+// is done or Close is called. It returns the error of the first dial, without retrying.
+// It may be called again after such an error, or once the client stopped reconnecting
+// because MaxReconnectAttempts was exhausted or ctx is done; otherwise it returns ErrClientStarted.
+func (c *ReconnectingWebSocketClient) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	if c.cancel != nil {
+		c.mu.Unlock()
+		return ErrClientStarted
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	c.setState(StateConnecting)
+	conn, err := c.dial(ctx)
+	if err != nil {
+		if errors.Is(err, ErrClientClosed) {
+			c.stop(StateClosed)
+		} else {
+			c.stop(StateDisconnected)
+		}
+		return err
+	}
+	go c.run(ctx, conn)
+	return nil
+}
+
+func (c *ReconnectingWebSocketClient) run(ctx context.Context, conn *websocket.Conn) {
+	for {
+		err := c.readLoop(conn)
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+		conn.Close()
+		if ctx.Err() != nil {
+			c.stop(StateClosed)
+			return
+		}
+		c.reportError(err)
+		c.setState(StateReconnecting)
+
+		conn = nil
+		for attempt := 0; conn == nil; attempt++ {
+			if c.MaxReconnectAttempts > 0 && attempt >= c.MaxReconnectAttempts {
+				c.stop(StateDisconnected)
+				return
+			}
+			if !sleepContext(ctx.Done(), c.Backoff.Delay(attempt)) {
+				c.stop(StateClosed)
+				return
+			}
+			if conn, err = c.dial(ctx); errors.Is(err, ErrClientClosed) {
+				c.stop(StateClosed)
+				return
+			} else if err != nil {
+				c.reportError(err)
+			}
+		}
+	}
+}
+
+// stop ends the connection loop started by Connect and moves to state, so that Connect
+// may be called again.
+func (c *ReconnectingWebSocketClient) stop(state ConnState) {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	from := c.state
+	c.state = state
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	if from != state && c.OnStateChange != nil {
+		c.OnStateChange(from, state)
+	}
+}
+
+// dial opens a connection, replays the subscriptions and publishes it as the current connection.
+func (c *ReconnectingWebSocketClient) dial(ctx context.Context) (*websocket.Conn, error) {
+	conn, _, err := c.Dialer.DialContext(ctx, c.URL, c.Header)
+	if err != nil {
+		return nil, err
+	}
+	if c.HeartbeatInterval > 0 {
+		deadline := c.HeartbeatInterval + c.PongTimeout
+		conn.SetReadDeadline(time.Now().Add(deadline))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(deadline))
+		})
+	}
+
+	// 订阅在锁外重放：连接尚未发布，不会与 Send 或心跳并发写入
+	conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+	for replayed := 0; ; {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			conn.Close()
+			return nil, ErrClientClosed
+		}
+		pending := c.subscriptions[replayed:]
+		if len(pending) == 0 {
+			// Close 之后不再发布连接，否则 readLoop 会泄漏
+			conn.SetWriteDeadline(time.Time{})
+			c.conn = conn
+			c.mu.Unlock()
+			break
+		}
+		c.mu.Unlock()
+		for _, frame := range pending {
+			if err := conn.WriteMessage(frame.messageType, frame.data); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		replayed += len(pending)
+	}
+	c.setState(StateConnected)
+	return conn, nil
+}
+
+func (c *ReconnectingWebSocketClient) readLoop(conn *websocket.Conn) error {
+	stopHeartbeat := make(chan struct{})
+	defer close(stopHeartbeat)
+	if c.HeartbeatInterval > 0 {
+		go c.heartbeat(conn, stopHeartbeat)
+	}
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if c.OnMessage != nil {
+			c.OnMessage(messageType, data)
+		}
+	}
+}
+
+func (c *ReconnectingWebSocketClient) heartbeat(conn *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(c.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.writeMu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.PongTimeout))
+			c.writeMu.Unlock()
+			if err != nil {
+				// The read loop notices the broken connection through its read deadline.
+				return
+			}
+		}
+	}
+}
+
+// Send writes a message to the current connection. It is safe for concurrent use.
+// Messages are not queued: ErrNotConnected is returned while reconnecting.
+func (c *ReconnectingWebSocketClient) Send(messageType int, data []byte) error {
+	c.mu.Lock()
+	conn, closed := c.conn, c.closed
+	c.mu.Unlock()
+	if closed {
+		return ErrClientClosed
+	}
+	if conn == nil {
+		return ErrNotConnected
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return conn.WriteMessage(messageType, data)
+}
+
+func (c *ReconnectingWebSocketClient) SendText(text string) error {
+	return c.Send(websocket.TextMessage, []byte(text))
+}
+
+func (c *ReconnectingWebSocketClient) SendJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(websocket.TextMessage, data)
+}
+
+// Subscribe registers a message that is sent now if connected, and again after every reconnection.
+func (c *ReconnectingWebSocketClient) Subscribe(messageType int, data []byte) error {
+	c.mu.Lock()
+	c.subscriptions = append(c.subscriptions, wsFrame{messageType: messageType, data: data})
+	c.mu.Unlock()
+	if err := c.Send(messageType, data); err != nil && !errors.Is(err, ErrNotConnected) {
+		return err
+	}
+	return nil
+}
+
+func (c *ReconnectingWebSocketClient) State() ConnState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Close sends a close frame, closes the connection and stops reconnecting.
+func (c *ReconnectingWebSocketClient) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn, cancel := c.conn, c.cancel
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if conn == nil {
+		c.setState(StateClosed)
+		return nil
+	}
+	c.writeMu.Lock()
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+	return conn.Close()
+}
+
+func (c *ReconnectingWebSocketClient) setState(state ConnState) {
+	c.mu.Lock()
+	from := c.state
+	c.state = state
+	c.mu.Unlock()
+	if from != state && c.OnStateChange != nil {
+		c.OnStateChange(from, state)
+	}
+}
+
+func (c *ReconnectingWebSocketClient) reportError(err error) {
+	if err != nil && c.OnError != nil {
+		c.OnError(err)
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReconnectingWebSocketClientReconnect(t *testing.T) {
+	var connections atomic.Int32
+	subscribed := make(chan string, 4)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		subscribed <- string(data)
+		if connections.Add(1) == 1 {
+			// Drop the first connection to force a reconnect.
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte("welcome back"))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	received := make(chan string, 1)
+	c := NewReconnectingWebSocketClient("ws"+strings.TrimPrefix(server.URL, "http"),
+		WithBackoff(Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2}),
+		WithOnMessage(func(messageType int, data []byte) {
+			received <- string(data)
+		}),
+	)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer c.Close()
+	if err := c.Subscribe(websocket.TextMessage, []byte("subscribe")); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg != "welcome back" {
+			t.Errorf("unexpected message %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not reconnect")
+	}
+	for i := 0; i < 2; i++ {
+		if sub := <-subscribed; sub != "subscribe" {
+			t.Errorf("expected subscription replay, got %q", sub)
+		}
+	}
+	if c.State() != StateConnected {
+		t.Errorf("expected connected state, got %s", c.State())
+	}
+}
+
+func TestReconnectingWebSocketClientConnectAndClose(t *testing.T) {
+	serverClosed := make(chan struct{}, 4)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				serverClosed <- struct{}{}
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	c := NewReconnectingWebSocketClient(url)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	if err := c.Connect(context.Background()); !errors.Is(err, ErrClientStarted) {
+		t.Errorf("expected ErrClientStarted, got %v", err)
+	}
+	c.Close()
+	<-serverClosed
+
+	// Close 发生在拨号完成与连接发布之间（重放订阅时）：新连接必须被关闭，而不是泄漏
+	var racing *ReconnectingWebSocketClient
+	dialer := *websocket.DefaultDialer
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		// 第一次写入是握手请求，第二次是订阅帧
+		return &hookedConn{Conn: conn, onWrite: map[int]func(){2: func() { racing.Close() }}}, nil
+	}
+	racing = NewReconnectingWebSocketClient(url, WithDialer(&dialer))
+	racing.Subscribe(websocket.TextMessage, []byte("subscribe"))
+	if err := racing.Connect(context.Background()); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+	select {
+	case <-serverClosed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection leaked after Close")
+	}
+	if state := racing.State(); state != StateClosed {
+		t.Errorf("expected closed state, got %s", state)
+	}
+}
+
+func TestReconnectingWebSocketClientConnectAfterGivingUp(t *testing.T) {
+	var accept atomic.Bool
+	accept.Store(true)
+	drop := make(chan struct{}, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !accept.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-drop
+	}))
+	defer server.Close()
+	defer close(drop)
+
+	disconnected := make(chan struct{}, 1)
+	c := NewReconnectingWebSocketClient("ws"+strings.TrimPrefix(server.URL, "http"),
+		WithBackoff(Backoff{Initial: time.Millisecond, Max: time.Millisecond, Multiplier: 1}),
+		WithMaxReconnectAttempts(2),
+		WithOnStateChange(func(from, to ConnState) {
+			if to == StateDisconnected {
+				disconnected <- struct{}{}
+			}
+		}),
+	)
+	defer c.Close()
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	accept.Store(false)
+	drop <- struct{}{}
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not give up reconnecting")
+	}
+
+	accept.Store(true)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatalf("expected Connect to restart the client, got %v", err)
+	}
+	if state := c.State(); state != StateConnected {
+		t.Errorf("expected connected state, got %s", state)
+	}
+}
+
+// hookedConn calls onWrite[n] before the n-th write.
+type hookedConn struct {
+	net.Conn
+	writes  int
+	onWrite map[int]func()
+}
+
+func (c *hookedConn) Write(b []byte) (int, error) {
+	c.writes++
+	if hook := c.onWrite[c.writes]; hook != nil {
+		hook()
+	}
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/vkviyu/nexus/transport/auth"
+	"github.com/vkviyu/nexus/transport/server/response"
+)
+
+// Authenticate authenticates every request with authenticator and stores the principal in
+// the request context, see auth.PrincipalFromContext. Rejected requests answer 401.
+func Authenticate(authenticator auth.Authenticator) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			if err != nil || principal == nil {
+				WriteError(w, r, response.Unauthorized("authentication required").WithCause(err))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.ContextWithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireRoles answers 403 unless the principal has at least one of roles, and 401 when the
+// request has no principal. It must run inside Authenticate or another middleware that stores
+// the principal.
+func RequireRoles(roles ...string) Middleware {
+	return requirePrincipal(func(principal *auth.Principal) bool {
+		for _, role := range roles {
+			if principal.HasRole(role) {
+				return true
+			}
+		}
+		return false
+	}, "requires one of the roles: "+strings.Join(roles, ", "))
+}
+
+// RequireScopes answers 403 unless the principal has every scope, and 401 when the request
+// has no principal, see RequireRoles.
+func RequireScopes(scopes ...string) Middleware {
+	return requirePrincipal(func(principal *auth.Principal) bool {
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				return false
+			}
+		}
+		return true
+	}, "requires the scopes: "+strings.Join(scopes, " "))
+}
+
+func requirePrincipal(allowed func(*auth.Principal) bool, message string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok || principal == nil {
+				WriteError(w, r, response.Unauthorized("authentication required"))
+				return
+			}
+			if !allowed(principal) {
+				WriteError(w, r, response.Forbidden(message))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vkviyu/nexus/transport/auth"
+)
+
+func TestRequireRolesAndScopes(t *testing.T) {
+	authenticator := auth.AuthenticatorFunc(func(r *http.Request) (*auth.Principal, error) {
+		switch r.Header.Get("X-User") {
+		case "admin":
+			return &auth.Principal{ID: "admin", Roles: []string{"admin"}, Scopes: []string{"users:read", "users:write"}}, nil
+		case "reader":
+			return &auth.Principal{ID: "reader", Roles: []string{"user"}, Scopes: []string{"users:read"}}, nil
+		}
+		return nil, auth.ErrUnauthenticated
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.ID))
+	})
+
+	router := NewRouter(Authenticate(authenticator))
+	router.Handle("GET /users", Chain(ok, RequireScopes("users:read")))
+	router.Handle("POST /users", Chain(ok, RequireScopes("users:read", "users:write")))
+	router.Group("/admin", RequireRoles("admin", "owner")).Handle("GET /stats", ok)
+	mux := router.ServeMux()
+
+	tests := []struct {
+		user       string
+		pattern    string
+		wantStatus int
+	}{
+		{"", "GET /users", http.StatusUnauthorized},
+		{"reader", "GET /users", http.StatusOK},
+		{"reader", "POST /users", http.StatusForbidden},
+		{"admin", "POST /users", http.StatusOK},
+		{"reader", "GET /admin/stats", http.StatusForbidden},
+		{"admin", "GET /admin/stats", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user+" "+tt.pattern, func(t *testing.T) {
+			method, path, _ := strings.Cut(tt.pattern, " ")
+			req := httptest.NewRequest(method, path, nil)
+			req.Header.Set("X-User", tt.user)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if rec.Code == http.StatusOK && rec.Body.String() != tt.user {
+				t.Errorf("expected principal %s, got %s", tt.user, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxBodySize limits the JSON body decoded by Bind, in bytes. Larger bodies fail with a
+// BindError answered with 413. Zero or negative disables the limit.
+var DefaultMaxBodySize int64 = 1 << 20
+
+// Bind decodes the JSON body of r into req, then the query parameters and path wildcards into
+// the fields tagged `query:"name"` and `path:"name"`, which take precedence over the body.
+// req must be a pointer to a struct. An empty body is not an error. The body is limited to
+// DefaultMaxBodySize.
+func Bind(r *http.Request, req any) error {
+	if r.Body != nil && r.Body != http.NoBody {
+		var body io.Reader = r.Body
+		if DefaultMaxBodySize > 0 {
+			body = http.MaxBytesReader(nil, r.Body, DefaultMaxBodySize)
+		}
+		if err := json.NewDecoder(body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+			return &BindError{Source: "body", Err: err}
+		}
+	}
+	value := reflect.ValueOf(req)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	query := r.URL.Query()
+	return bindFields(value.Elem(), func(field reflect.StructField) ([]string, string, bool) {
+		if name, ok := field.Tag.Lookup("query"); ok {
+			values, present := query[name]
+			return values, "query", present
+		}
+		if name, ok := field.Tag.Lookup("path"); ok {
+			raw := r.PathValue(name)
+			return []string{raw}, "path", raw != ""
+		}
+		return nil, "", false
+	})
+}
+
+// bindFields sets the tagged fields of a struct, recursing into embedded structs.
+func bindFields(value reflect.Value, lookup func(reflect.StructField) ([]string, string, bool)) error {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindFields(value.Field(i), lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		values, source, ok := lookup(field)
+		if !ok {
+			continue
+		}
+		if err := setField(value.Field(i), values); err != nil {
+			return &BindError{Source: source, Field: field.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, raw := range values {
+			if err := setValue(slice.Index(i), raw); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return setValue(field, values[0])
+}
+
+func setValue(value reflect.Value, raw string) error {
+	if value.Kind() == reflect.Pointer {
+		ptr := reflect.New(value.Type().Elem())
+		if err := setValue(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		value.Set(ptr)
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.ToLower(raw))
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
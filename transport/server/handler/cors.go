@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowedOrigins lists the allowed origins, "*" allows any origin.
+	AllowedOrigins []string
+	// AllowedMethods defaults to DefaultCORSMethods.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed in preflight requests; when empty the
+	// headers requested by the client are allowed.
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long preflight results may be cached, not sent if zero.
+	MaxAge time.Duration
+}
+
+var DefaultCORSMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+func (c *CORSConfig) originAllowed(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, "*") || slices.Contains(c.AllowedOrigins, origin)
+}
+
+// CORS answers preflight requests and sets the CORS headers of requests from allowed origins.
+// Requests from other origins are passed through without CORS headers, so browsers block them.
+func CORS(config CORSConfig) Middleware {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = DefaultCORSMethods
+	}
+	methods := strings.Join(config.AllowedMethods, ", ")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			if origin == "" || !config.originAllowed(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// 携带凭证时不能返回 "*"
+			if slices.Contains(config.AllowedOrigins, "*") && !config.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if config.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !preflight {
+				if len(config.ExposedHeaders) > 0 {
+					header.Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", methods)
+			if len(config.AllowedHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+			if config.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
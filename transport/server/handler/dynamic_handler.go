@@ -3,46 +3,94 @@ package handler
 import (
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 type DynamicHandlerConfig struct {
 	// Get is the handler function for GET requests, with query parameters.
+	// HEAD requests are answered with it when Head is nil.
 	Get func(w http.ResponseWriter, r *http.Request, query url.Values)
+	// Head is the handler function for HEAD requests, with query parameters.
+	Head func(w http.ResponseWriter, r *http.Request, query url.Values)
 	// Post is the handler function for POST requests
 	Post func(w http.ResponseWriter, r *http.Request)
 	// Put is the handler function for PUT requests
 	Put func(w http.ResponseWriter, r *http.Request)
+	// Patch is the handler function for PATCH requests
+	Patch func(w http.ResponseWriter, r *http.Request)
 	// Delete is the handler function for DELETE requests, with query parameters.
 	Delete func(w http.ResponseWriter, r *http.Request, query url.Values)
+	// Options is the handler function for OPTIONS requests. When nil, OPTIONS requests are
+	// answered with 204 and the Allow header.
+	Options func(w http.ResponseWriter, r *http.Request)
+	// Connect is the handler function for CONNECT requests
+	Connect func(w http.ResponseWriter, r *http.Request)
+	// Trace is the handler function for TRACE requests
+	Trace func(w http.ResponseWriter, r *http.Request)
+	// Middlewares wrap the handler of every method, outside MethodMiddlewares.
+	Middlewares []Middleware
+	// MethodMiddlewares wrap the handler of a single method, keyed by http.MethodXxx.
+	MethodMiddlewares map[string][]Middleware
 }
 
+// NewDynamicHandler dispatches requests to the handler function of their method. Methods
+// without a handler function answer 405, and every response carries the Allow header.
 func NewDynamicHandler(config DynamicHandlerConfig) http.HandlerFunc {
+	handlers := make(map[string]http.Handler)
+	var allowed []string
+	add := func(method string, handler http.HandlerFunc) {
+		handlers[method] = Chain(Chain(handler, config.MethodMiddlewares[method]...), config.Middlewares...)
+		allowed = append(allowed, method)
+	}
+	withQuery := func(fn func(http.ResponseWriter, *http.Request, url.Values)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fn(w, r, r.URL.Query())
+		}
+	}
+
+	if config.Get != nil {
+		add(http.MethodGet, withQuery(config.Get))
+	}
+	// net/http 会丢弃 HEAD 响应的 body，直接复用 GET 即可
+	if config.Head != nil {
+		add(http.MethodHead, withQuery(config.Head))
+	} else if config.Get != nil {
+		add(http.MethodHead, withQuery(config.Get))
+	}
+	if config.Post != nil {
+		add(http.MethodPost, config.Post)
+	}
+	if config.Put != nil {
+		add(http.MethodPut, config.Put)
+	}
+	if config.Patch != nil {
+		add(http.MethodPatch, config.Patch)
+	}
+	if config.Delete != nil {
+		add(http.MethodDelete, withQuery(config.Delete))
+	}
+	if config.Connect != nil {
+		add(http.MethodConnect, config.Connect)
+	}
+	if config.Trace != nil {
+		add(http.MethodTrace, config.Trace)
+	}
+	if config.Options != nil {
+		add(http.MethodOptions, config.Options)
+	} else {
+		add(http.MethodOptions, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	allow := strings.Join(allowed, ", ")
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			if config.Get != nil {
-				config.Get(w, r, r.URL.Query())
-				return
-			}
-		case http.MethodPost:
-			if config.Post != nil {
-				config.Post(w, r)
-				return
-			}
-		case http.MethodPut:
-			if config.Put != nil {
-				config.Put(w, r)
-				return
-			}
-		case http.MethodDelete:
-			if config.Delete != nil {
-				config.Delete(w, r, r.URL.Query())
-				return
-			}
-		default:
+		w.Header().Set("Allow", allow)
+		handler, ok := handlers[r.Method]
+		if !ok {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		handler.ServeHTTP(w, r)
 	}
 }
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDynamicHandler(t *testing.T) {
+	h := NewDynamicHandler(DynamicHandlerConfig{
+		Get: func(w http.ResponseWriter, r *http.Request, query url.Values) {
+			w.Header().Set("X-Name", query.Get("name"))
+			fmt.Fprint(w, "get ", query.Get("name"))
+		},
+		Patch: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "patch")
+		},
+		Middlewares:       []Middleware{tagMiddleware("all")},
+		MethodMiddlewares: map[string][]Middleware{http.MethodPatch: {tagMiddleware("patch")}},
+	})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	const allow = "GET, HEAD, PATCH, OPTIONS"
+	tests := []struct {
+		method     string
+		wantStatus int
+		wantBody   string
+		wantTrace  string
+	}{
+		{http.MethodGet, http.StatusOK, "get nexus", "all"},
+		{http.MethodHead, http.StatusOK, "", "all"},
+		{http.MethodPatch, http.StatusOK, "patch", "all,patch"},
+		{http.MethodOptions, http.StatusNoContent, "", "all"},
+		{http.MethodPost, http.StatusMethodNotAllowed, "", ""},
+		{http.MethodDelete, http.StatusMethodNotAllowed, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, server.URL+"?name=nexus", nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+			got := string(data)
+			if resp.StatusCode != tt.wantStatus || got != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", resp.StatusCode, got, tt.wantStatus, tt.wantBody)
+			}
+			if resp.Header.Get("Allow") != allow {
+				t.Errorf("unexpected Allow %q", resp.Header.Get("Allow"))
+			}
+			if trace := strings.Join(resp.Header.Values("X-Trace"), ","); trace != tt.wantTrace {
+				t.Errorf("expected trace %q, got %q", tt.wantTrace, trace)
+			}
+			if tt.method == http.MethodHead && resp.Header.Get("X-Name") != "nexus" {
+				t.Errorf("expected HEAD to reuse GET headers")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/vkviyu/nexus/transport/server/response"
+)
+
+var errMissingPathParam = errors.New("missing")
+
+// PathParamError indicates that a path parameter is missing or has an invalid value.
+type PathParamError struct {
+	Name  string
+	Value string
+	Err   error
+}
+
+func (e *PathParamError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("path parameter %s: %v", e.Name, e.Err)
+	}
+	return fmt.Sprintf("path parameter %s=%q: %v", e.Name, e.Value, e.Err)
+}
+
+func (e *PathParamError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PathParamError) APIError() *response.Error {
+	return response.BadRequest(e.Error()).WithCause(e.Err)
+}
+
+// BindError indicates that the request could not be decoded into the handler's input.
+type BindError struct {
+	// Source is "body", "query" or "path".
+	Source string
+	Field  string
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid %s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("invalid %s parameter %s: %v", e.Source, e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BindError) APIError() *response.Error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(e.Err, &tooLarge) {
+		return response.NewError(http.StatusRequestEntityTooLarge, response.CodeInvalidRequest, e.Error()).WithCause(e.Err)
+	}
+	return response.BadRequest(e.Error()).WithCause(e.Err)
+}
+
+// Errors that JSON handlers can return, directly or wrapped, to answer with the matching status.
+// The message of the returned error is kept, so fmt.Errorf("user %d: %w", id, ErrNotFound) works.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+)
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/vkviyu/nexus/transport/server/response"
+)
+
+// Gzip compresses responses for clients that accept gzip. Responses that already set a
+// Content-Encoding, responses without a body and protocol upgrades (e.g. websocket) are
+// left untouched.
+// level is a compress/gzip level, gzip.DefaultCompression is a good default.
+func Gzip(level int) Middleware {
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		level = gzip.DefaultCompression
+	}
+	pool := &sync.Pool{
+		New: func() any {
+			gz, _ := gzip.NewWriterLevel(nil, level)
+			return gz
+		},
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !response.AcceptsGzip(r) || r.Method == http.MethodHead ||
+				headerHasToken(r.Header, "Connection", "upgrade") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipResponseWriter{ResponseWriter: w, pool: pool}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	pool        *sync.Pool
+	gz          *gzip.Writer
+	wroteHeader bool
+	compress    bool
+	hijacked    bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		// 1xx 信息响应（如 103 Early Hints）之后还会有最终响应
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	header := w.Header()
+	header.Add("Vary", "Accept-Encoding")
+	w.compress = header.Get("Content-Encoding") == "" &&
+		code >= http.StatusOK && code != http.StatusNoContent && code != http.StatusNotModified
+	if w.compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		// 压缩后 net/http 无法再嗅探类型，需在压缩前设置
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compress {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.gz == nil {
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	return w.gz.Write(b)
+}
+
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection over uncompressed, e.g. for a websocket upgrade.
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+func (w *gzipResponseWriter) close() {
+	if !w.compress || w.hijacked {
+		return
+	}
+	if w.gz == nil {
+		// 即使没有 body 也要输出合法的 gzip 流
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.gz.Close()
+	w.pool.Put(w.gz)
+	w.gz = nil
+}
+
+// headerHasToken reports whether the comma separated values of header contain token,
+// case-insensitively.
+func headerHasToken(h http.Header, header, token string) bool {
+	for _, value := range h.Values(header) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
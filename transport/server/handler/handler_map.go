@@ -4,12 +4,26 @@ import "net/http"
 
 type HandlerMap map[string]http.Handler
 
-func GetServeMux(handlerMap HandlerMap) *http.ServeMux {
+// GetServeMux registers every pattern of handlerMap on a new ServeMux, wrapped with the
+// middlewares given by WithPatternMiddleware. The middlewares given by WithMiddleware wrap
+// the whole mux, so they also run for its own 404 and 405 responses, e.g. CORS preflight
+// requests. In that case the returned mux routes "/" to the wrapped one, and further
+// patterns registered on it bypass those middlewares.
+func GetServeMux(handlerMap HandlerMap, options ...MuxOption) *http.ServeMux {
+	config := &muxConfig{patternMiddlewares: make(map[string][]Middleware)}
+	for _, option := range options {
+		option(config)
+	}
 	mux := http.NewServeMux()
 	for pattern, handler := range handlerMap {
-		mux.Handle(pattern, handler)
+		mux.Handle(pattern, Chain(handler, config.patternMiddlewares[pattern]...))
+	}
+	if len(config.middlewares) == 0 {
+		return mux
 	}
-	return mux
+	outer := http.NewServeMux()
+	outer.Handle("/", Chain(mux, config.middlewares...))
+	return outer
 }
 
 func NewHandlerMap() HandlerMap {
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/vkviyu/nexus/transport/server/response"
+	"github.com/vkviyu/nexus/utils/jsonutil"
+)
+
+// StatusCoder can be implemented by a response to answer with a status other than 200,
+// for example 201 Created.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+type requestKey struct{}
+
+// RequestFromContext returns the request handled by a JSON handler.
+func RequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestKey{}).(*http.Request)
+	return r
+}
+
+// JSON adapts fn to an http.Handler. The request is bound into Req with Bind and validated
+// with jsonutil.ValidateModel, then the result of fn is written as JSON: 200 (or the status of
+// a StatusCoder), 204 when it is nil, or the error with WriteError.
+func JSON[Req, Resp any](fn func(ctx context.Context, req *Req) (*Resp, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := new(Req)
+		if err := Bind(r, req); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		if err := jsonutil.ValidateModel(req); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		resp, err := fn(context.WithValue(r.Context(), requestKey{}, r), req)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		status := http.StatusOK
+		if coder, ok := any(resp).(StatusCoder); ok {
+			status = coder.StatusCode()
+		}
+		response.WriteJSONResponse(w, resp, status)
+	}
+}
+
+// WriteError writes err as a response.Error, in the problem format when the client accepts
+// application/problem+json and in response.DefaultErrorFormat otherwise. The request ID of
+// the RequestID middleware is used as the trace ID. Besides the conversions of
+// response.FromError, ErrUnauthorized, ErrForbidden, ErrNotFound and ErrConflict, wrapped or
+// not, answer 401, 403, 404 and 409 with the error message.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(err)
+	if apiErr.TraceID == "" {
+		apiErr.TraceID = RequestIDFromContext(r.Context())
+	}
+	format := response.DefaultErrorFormat
+	if strings.Contains(r.Header.Get("Accept"), response.ProblemContentType) {
+		format = response.ErrorFormatProblem
+	}
+	response.WriteErrorFormat(w, apiErr, format)
+}
+
+func toAPIError(err error) *response.Error {
+	var apiErr response.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return response.FromError(err)
+	case errors.Is(err, ErrUnauthorized):
+		return response.Unauthorized(err.Error()).WithCause(err)
+	case errors.Is(err, ErrForbidden):
+		return response.Forbidden(err.Error()).WithCause(err)
+	case errors.Is(err, ErrNotFound):
+		return response.NotFound(err.Error()).WithCause(err)
+	case errors.Is(err, ErrConflict):
+		return response.Conflict(err.Error()).WithCause(err)
+	}
+	return response.FromError(err)
+}
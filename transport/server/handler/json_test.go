@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vkviyu/nexus/transport/server/response"
+)
+
+type updateUserReq struct {
+	ID     int64    `path:"id"`
+	Notify bool     `query:"notify"`
+	Tags   []string `query:"tag"`
+	Name   string   `json:"name" validate:"required"`
+	Age    int      `json:"age" validate:"gte=0"`
+}
+
+type updateUserResp struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Notify bool     `json:"notify"`
+	Tags   []string `json:"tags"`
+}
+
+func TestJSON(t *testing.T) {
+	router := NewRouter()
+	router.Handle("PUT /users/{id}", JSON(func(ctx context.Context, req *updateUserReq) (*updateUserResp, error) {
+		if RequestFromContext(ctx) == nil {
+			return nil, errors.New("missing request in context")
+		}
+		switch req.ID {
+		case 404:
+			return nil, fmt.Errorf("user %d: %w", req.ID, ErrNotFound)
+		case 409:
+			return nil, ErrConflict
+		case 402:
+			return nil, response.NewError(http.StatusPaymentRequired, "quota_exceeded", "quota exceeded")
+		case 500:
+			return nil, errors.New("database password leaked")
+		case 204:
+			return nil, nil
+		}
+		return &updateUserResp{ID: req.ID, Name: req.Name, Notify: req.Notify, Tags: req.Tags}, nil
+	}))
+	mux := router.ServeMux()
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"ok", "/users/1?notify=true&tag=a&tag=b", `{"name":"alice"}`, http.StatusOK, `{"id":1,"name":"alice","notify":true,"tags":["a","b"]}`},
+		{"no content", "/users/204", `{"name":"alice"}`, http.StatusNoContent, ``},
+		{"invalid json", "/users/1", `{"name":`, http.StatusBadRequest, `"code":"invalid_request"`},
+		{"invalid path", "/users/abc", `{"name":"alice"}`, http.StatusBadRequest, `invalid path parameter ID`},
+		{"invalid query", "/users/1?notify=maybe", `{"name":"alice"}`, http.StatusBadRequest, `invalid query parameter Notify`},
+		{"validation", "/users/1", `{"age":-1}`, http.StatusBadRequest, `"fieldErrors":[{"field":"name","rule":"required"`},
+		{"not found", "/users/404", `{"name":"alice"}`, http.StatusNotFound, `"message":"user 404: not found"`},
+		{"conflict", "/users/409", `{"name":"alice"}`, http.StatusConflict, `"code":"conflict"`},
+		{"custom", "/users/402", `{"name":"alice"}`, http.StatusPaymentRequired, `"code":"quota_exceeded"`},
+		{"internal", "/users/500", `{"name":"alice"}`, http.StatusInternalServerError, `"message":"internal server error"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %s, got %s", tt.wantBody, rec.Body)
+			}
+			if rec.Code >= 400 {
+				var envelope response.ErrorEnvelope
+				if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil || envelope.Error == nil || envelope.Error.Code == "" {
+					t.Errorf("expected error envelope, got %s", rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteErrorProblem(t *testing.T) {
+	handler := Chain(JSON(func(ctx context.Context, req *struct{}) (*struct{}, error) {
+		return nil, fmt.Errorf("order 7: %w", ErrNotFound)
+	}), RequestID())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", response.ProblemContentType)
+	req.Header.Set(RequestIDHeader, "trace-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != response.ProblemContentType {
+		t.Fatalf("expected problem content type, got %q", ct)
+	}
+	var problem response.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("invalid problem %s: %v", rec.Body, err)
+	}
+	want := response.Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "order 7: not found", Code: "not_found", TraceID: "trace-1"}
+	if fmt.Sprint(problem) != fmt.Sprint(want) {
+		t.Errorf("got %+v, want %+v", problem, want)
+	}
+}
+
+func TestBindMaxBodySize(t *testing.T) {
+	defer func(size int64) { DefaultMaxBodySize = size }(DefaultMaxBodySize)
+	DefaultMaxBodySize = 16
+
+	h := JSON(func(ctx context.Context, req *updateUserReq) (*updateUserResp, error) {
+		return &updateUserResp{Name: req.Name}, nil
+	})
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"within limit", `{"name":"bob"}`, http.StatusOK},
+		{"too large", `{"name":"` + strings.Repeat("a", 32) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+		})
+	}
+}
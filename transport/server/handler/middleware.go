@@ -0,0 +1,37 @@
+package handler
+
+import "net/http"
+
+// Middleware wraps an http.Handler to add behavior before or after it.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps handler with middlewares, the first middleware being the outermost.
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// MuxOption configures how GetServeMux builds the mux.
+type MuxOption func(*muxConfig)
+
+type muxConfig struct {
+	middlewares        []Middleware
+	patternMiddlewares map[string][]Middleware
+}
+
+// WithMiddleware applies middlewares to every request of the mux, including those that
+// match no pattern.
+func WithMiddleware(middlewares ...Middleware) MuxOption {
+	return func(c *muxConfig) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// WithPatternMiddleware applies middlewares to a single pattern, inside the global ones.
+func WithPatternMiddleware(pattern string, middlewares ...Middleware) MuxOption {
+	return func(c *muxConfig) {
+		c.patternMiddlewares[pattern] = append(c.patternMiddlewares[pattern], middlewares...)
+	}
+}
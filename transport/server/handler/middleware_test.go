@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+func tagMiddleware(tag string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", tag)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestGetServeMuxMiddleware(t *testing.T) {
+	handlerMap := NewHandlerMap()
+	handlerMap.AddFunc("/a", func(w http.ResponseWriter, r *http.Request) {})
+	handlerMap.AddFunc("/b", func(w http.ResponseWriter, r *http.Request) {})
+	mux := GetServeMux(handlerMap,
+		WithMiddleware(tagMiddleware("global1"), tagMiddleware("global2")),
+		WithPatternMiddleware("/a", tagMiddleware("a")),
+	)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/a", "global1,global2,a"},
+		{"/b", "global1,global2"},
+		{"/missing", "global1,global2"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRecoveryAndAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), RequestID(), AccessLog(logger), Recovery(logger))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+	if rec.Header().Get(RequestIDHeader) != "req-1" {
+		t.Errorf("expected request id to be echoed, got %q", rec.Header().Get(RequestIDHeader))
+	}
+	for _, want := range []string{`"msg":"panic: boom"`, `"status":500`, `"request_id":"req-1"`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected log to contain %s, got %s", want, logs.String())
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	handler := CORS(CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{"preflight", http.MethodOptions, "https://app.example.com", http.StatusNoContent, "https://app.example.com"},
+		{"simple", http.MethodGet, "https://app.example.com", http.StatusTeapot, "https://app.example.com"},
+		{"other origin", http.MethodOptions, "https://evil.com", http.StatusTeapot, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("expected allow origin %q, got %q", tt.wantOrigin, got)
+			}
+		})
+	}
+}
+
+func TestCORSPreflightThroughRouter(t *testing.T) {
+	router := NewRouter(CORS(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	router.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	for _, mux := range []*http.ServeMux{
+		router.ServeMux(),
+		NewRouter().ServeMux(WithMiddleware(CORS(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}))),
+	} {
+		// 路由只注册了 GET，预检请求由中间件在 mux 返回 405 之前应答
+		req := httptest.NewRequest(http.MethodOptions, "/users", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Errorf("expected preflight to be answered, got %d %v", rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	body := strings.Repeat("hello nexus ", 100)
+	handler := Gzip(gzip.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got headers %v", rec.Header())
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("expected sniffed content type, got %q", rec.Header().Get("Content-Type"))
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	if data, _ := io.ReadAll(reader); string(data) != body {
+		t.Errorf("unexpected body %q", data)
+	}
+
+	for _, acceptEncoding := range []string{"", "gzip;q=0", "deflate, gzip;q=0.0", "*, gzip;q=0", "identity"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != body {
+			t.Errorf("expected uncompressed body for Accept-Encoding %q", acceptEncoding)
+		}
+	}
+}
+
+// codeRecorder records every status code written, including informational ones.
+type codeRecorder struct {
+	*httptest.ResponseRecorder
+	codes []int
+}
+
+func (r *codeRecorder) WriteHeader(code int) {
+	r.codes = append(r.codes, code)
+	if code >= 200 {
+		r.ResponseRecorder.WriteHeader(code)
+	}
+}
+
+func TestGzipInformationalResponse(t *testing.T) {
+	handler := Gzip(gzip.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</app.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "created")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := &codeRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, req)
+	if !slices.Equal(rec.codes, []int{http.StatusEarlyHints, http.StatusCreated}) {
+		t.Fatalf("expected 103 then 201, got %v", rec.codes)
+	}
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("expected the final response to be compressed, got headers %v", rec.Header())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	handler := Timeout(10*time.Millisecond, "too slow")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "too slow" {
+		t.Errorf("expected 503 too slow, got %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestWebsocketThroughMiddleware(t *testing.T) {
+	logged := make(chan string, 1)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.AddHook(logHook(func(entry *logrus.Entry) {
+		logged <- fmt.Sprint(entry.Data["status"])
+	}))
+
+	upgrader := websocket.Upgrader{}
+	handlerMap := NewHandlerMap()
+	handlerMap.AddFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(messageType, data)
+	})
+	server := httptest.NewServer(GetServeMux(handlerMap,
+		WithMiddleware(RequestID(), AccessLog(logger), Recovery(logger), Gzip(gzip.DefaultCompression)),
+	))
+	defer server.Close()
+
+	header := http.Header{"Accept-Encoding": {"gzip"}}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial failed with status %d: %v", status, err)
+	}
+	defer conn.Close()
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("expected no content encoding on upgrade, got %q", resp.Header.Get("Content-Encoding"))
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "ping" {
+		t.Fatalf("expected echo, got %q, %v", data, err)
+	}
+	conn.Close()
+
+	// AccessLog 在 handler 返回后记录，等待连接关闭
+	select {
+	case status := <-logged:
+		if status != "101" {
+			t.Errorf("expected access log with status 101, got %s", status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("expected an access log entry")
+	}
+}
+
+type logHook func(entry *logrus.Entry)
+
+func (h logHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h logHook) Fire(entry *logrus.Entry) error {
+	h(entry)
+	return nil
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader is the header read and written by the RequestID middleware.
+var RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID set by the RequestID middleware.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// RequestID keeps the incoming RequestIDHeader or generates a UUID, echoes it in the response
+// and stores it in the request context, see RequestIDFromContext.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" {
+				requestID = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+		})
+	}
+}
+
+// Recovery turns a panic into a 500 response and logs it with the stack trace.
+// A nil logger uses the logrus standard logger.
+func Recovery(logger logrus.FieldLogger) Middleware {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				logger.WithFields(logrus.Fields{
+					"method":     r.Method,
+					"path":       r.URL.Path,
+					"request_id": RequestIDFromContext(r.Context()),
+					"stack":      string(debug.Stack()),
+				}).Errorf("panic: %v", recovered)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// statusRecorder records the status code and body size written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *statusRecorder) Flush() {
+	http.NewResponseController(s.ResponseWriter).Flush()
+}
+
+// Hijack lets websocket upgrades pass through the middleware.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(s.ResponseWriter).Hijack()
+	if err == nil && s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// AccessLog logs one entry per request with its method, path, status, size and duration.
+// A nil logger uses the logrus standard logger.
+func AccessLog(logger logrus.FieldLogger) Middleware {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+			if recorder.status == 0 {
+				recorder.status = http.StatusOK
+			}
+			logger.WithFields(logrus.Fields{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      recorder.status,
+				"bytes":       recorder.bytes,
+				"duration_ms": time.Since(start).Milliseconds(),
+				"remote_addr": r.RemoteAddr,
+				"request_id":  RequestIDFromContext(r.Context()),
+			}).Info("access")
+		})
+	}
+}
+
+// Timeout answers 503 with message when the handler runs longer than timeout, see
+// http.TimeoutHandler. The wrapped handler cannot flush or hijack the connection.
+func Timeout(timeout time.Duration, message string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, timeout, message)
+	}
+}
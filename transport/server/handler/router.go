@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Router builds a HandlerMap from Go 1.22 ServeMux patterns ("GET /users/{id}"), organized
+// in nested groups that share a path prefix and middlewares.
+//
+//	router := handler.NewRouter(handler.RequestID())
+//	api := router.Group("/api", auth)
+//	api.Get("/users/{id}", getUser)
+//	mux := router.ServeMux()
+type Router struct {
+	prefix      string
+	middlewares []Middleware
+	parent      *Router
+	routes      *[]route
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+	group   *Router
+}
+
+// NewRouter returns a root Router whose middlewares apply to every route.
+func NewRouter(middlewares ...Middleware) *Router {
+	return &Router{
+		middlewares: middlewares,
+		routes:      new([]route),
+	}
+}
+
+// Use appends middlewares to the router; they apply to routes registered before and after.
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Group returns a child router whose routes are prefixed with prefix and wrapped with
+// middlewares, inside the middlewares of r.
+func (r *Router) Group(prefix string, middlewares ...Middleware) *Router {
+	return &Router{
+		prefix:      path.Join("/", r.prefix, prefix),
+		middlewares: middlewares,
+		parent:      r,
+		routes:      r.routes,
+	}
+}
+
+// Route calls fn with a new group, for declaring nested routes in a block.
+func (r *Router) Route(prefix string, fn func(group *Router), middlewares ...Middleware) *Router {
+	group := r.Group(prefix, middlewares...)
+	fn(group)
+	return group
+}
+
+// Handle registers handler for pattern, "[METHOD ]/path" relative to the router's prefix.
+// Like http.ServeMux, it panics when the pattern is registered twice.
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	method, routePath, hasMethod := strings.Cut(pattern, " ")
+	if !hasMethod {
+		method, routePath = "", pattern
+	}
+	routePath = joinPath(r.prefix, strings.TrimSpace(routePath))
+	if hasMethod {
+		routePath = method + " " + routePath
+	}
+	for _, existing := range *r.routes {
+		if existing.pattern == routePath {
+			panic(fmt.Sprintf("handler: pattern %q registered twice", routePath))
+		}
+	}
+	*r.routes = append(*r.routes, route{pattern: routePath, handler: handler, group: r})
+}
+
+func (r *Router) HandleFunc(pattern string, handlerFunc func(http.ResponseWriter, *http.Request)) {
+	r.Handle(pattern, http.HandlerFunc(handlerFunc))
+}
+
+func (r *Router) Get(path string, handlerFunc http.HandlerFunc) {
+	r.Handle(http.MethodGet+" "+path, handlerFunc)
+}
+
+func (r *Router) Post(path string, handlerFunc http.HandlerFunc) {
+	r.Handle(http.MethodPost+" "+path, handlerFunc)
+}
+
+func (r *Router) Put(path string, handlerFunc http.HandlerFunc) {
+	r.Handle(http.MethodPut+" "+path, handlerFunc)
+}
+
+func (r *Router) Patch(path string, handlerFunc http.HandlerFunc) {
+	r.Handle(http.MethodPatch+" "+path, handlerFunc)
+}
+
+func (r *Router) Delete(path string, handlerFunc http.HandlerFunc) {
+	r.Handle(http.MethodDelete+" "+path, handlerFunc)
+}
+
+// chain returns the middlewares of the router and its ancestors, outermost first.
+func (r *Router) chain() []Middleware {
+	if r.parent == nil {
+		return r.middlewares
+	}
+	return slices.Concat(r.parent.chain(), r.middlewares)
+}
+
+// HandlerMap returns the registered routes with their group middlewares applied.
+func (r *Router) HandlerMap() HandlerMap {
+	handlerMap := NewHandlerMap()
+	for _, route := range *r.routes {
+		handlerMap.Add(route.pattern, Chain(route.handler, route.group.chain()...))
+	}
+	return handlerMap
+}
+
+// ServeMux builds a ServeMux from the routes, see GetServeMux for the options. The middlewares
+// of the root router wrap the whole mux, inside those of WithMiddleware, so that they also run
+// for requests that match no route, e.g. CORS preflight requests and 404 responses.
+func (r *Router) ServeMux(options ...MuxOption) *http.ServeMux {
+	root := r
+	for root.parent != nil {
+		root = root.parent
+	}
+	handlerMap := NewHandlerMap()
+	for _, route := range *r.routes {
+		handlerMap.Add(route.pattern, Chain(route.handler, route.group.chain()[len(root.middlewares):]...))
+	}
+	return GetServeMux(handlerMap, append(slices.Clip(options), WithMiddleware(root.middlewares...))...)
+}
+
+// joinPath joins a group prefix and a route path into a clean pattern path. The result ends
+// with a slash, matching a subtree, only when routePath does; the bare root of a group
+// mounted at "/" becomes "/{$}" so that it does not catch every request.
+func joinPath(prefix, routePath string) string {
+	joined := path.Join("/", prefix, routePath)
+	switch {
+	case !strings.HasSuffix(routePath, "/"):
+		if joined == "/" {
+			return "/{$}"
+		}
+		return joined
+	case joined == "/":
+		return joined
+	}
+	return joined + "/"
+}
+
+// PathValue is the set of types PathParam can parse.
+type PathValue interface {
+	~string | ~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64 | ~float64 | ~bool
+}
+
+// PathParam parses the path wildcard name of r, such as {id} in "GET /users/{id}", as T.
+// It returns a *PathParamError when the wildcard is missing or cannot be parsed.
+func PathParam[T PathValue](r *http.Request, name string) (T, error) {
+	var result T
+	raw := r.PathValue(name)
+	if raw == "" {
+		return result, &PathParamError{Name: name, Err: errMissingPathParam}
+	}
+	value := reflect.ValueOf(&result).Elem()
+	var err error
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(raw, 10, value.Type().Bits()); err == nil {
+			value.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(raw, 10, value.Type().Bits()); err == nil {
+			value.SetUint(n)
+		}
+	case reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(raw, 64); err == nil {
+			value.SetFloat(f)
+		}
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(raw); err == nil {
+			value.SetBool(b)
+		}
+	}
+	if err != nil {
+		return result, &PathParamError{Name: name, Value: raw, Err: err}
+	}
+	return result, nil
+}
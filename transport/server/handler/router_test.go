@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	router := NewRouter(tagMiddleware("root"))
+	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	api := router.Group("/api/", tagMiddleware("api"))
+	api.Route("/users", func(users *Router) {
+		users.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			id, err := PathParam[int64](r, "id")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, "user ", id)
+		})
+		users.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}, tagMiddleware("users"))
+	// 在注册路由之后追加的中间件同样生效
+	api.Use(tagMiddleware("late"))
+	mux := router.ServeMux()
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantTrace  string
+	}{
+		{http.MethodGet, "/health", http.StatusOK, "ok", "root"},
+		{http.MethodGet, "/api/users/42", http.StatusOK, "user 42", "root,api,late,users"},
+		{http.MethodGet, "/api/users/abc", http.StatusBadRequest, `path parameter id="abc"`, "root,api,late,users"},
+		{http.MethodDelete, "/api/users/42", http.StatusNoContent, "", "root,api,late,users"},
+		{http.MethodPost, "/api/users/42", http.StatusMethodNotAllowed, "", "root"},
+		{http.MethodGet, "/missing", http.StatusNotFound, "", "root"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.wantBody, rec.Body)
+			}
+			if got := strings.Join(rec.Header().Values("X-Trace"), ","); got != tt.wantTrace {
+				t.Errorf("expected trace %q, got %q", tt.wantTrace, got)
+			}
+		})
+	}
+}
+
+func TestRouterDuplicatePattern(t *testing.T) {
+	router := NewRouter()
+	router.Get("/a", func(w http.ResponseWriter, r *http.Request) {})
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate pattern")
+		}
+	}()
+	router.Group("/").Get("a", func(w http.ResponseWriter, r *http.Request) {})
+}
+
+func TestRouterRootGroup(t *testing.T) {
+	router := NewRouter()
+	root := router.Group("/")
+	root.Get("", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "index")
+	})
+	root.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "users")
+	})
+	root.Group("//static/").Get("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "static")
+	})
+
+	var patterns []string
+	for pattern := range router.HandlerMap() {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns)
+	if want := []string{"GET /static/", "GET /users", "GET /{$}"}; !slices.Equal(patterns, want) {
+		t.Errorf("expected patterns %q, got %q", want, patterns)
+	}
+
+	mux := router.ServeMux()
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/", http.StatusOK, "index"},
+		{"/users", http.StatusOK, "users"},
+		{"/static/app.js", http.StatusOK, "static"},
+		{"/unknown", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.wantBody, rec.Body)
+			}
+		})
+	}
+}
+
+func TestPathParam(t *testing.T) {
+	type userId uint32
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetPathValue("id", "7")
+	req.SetPathValue("flag", "maybe")
+
+	if id, err := PathParam[userId](req, "id"); err != nil || id != 7 {
+		t.Errorf("expected 7, got %v %v", id, err)
+	}
+	var numErr *strconv.NumError
+	if _, err := PathParam[bool](req, "flag"); !errors.As(err, &numErr) {
+		t.Errorf("expected strconv error, got %v", err)
+	}
+	var paramErr *PathParamError
+	if _, err := PathParam[string](req, "missing"); !errors.As(err, &paramErr) || paramErr.Name != "missing" {
+		t.Errorf("expected PathParamError, got %v", err)
+	}
+}
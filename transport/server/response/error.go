@@ -0,0 +1,228 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Error codes used by the constructors.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeInternal         = "internal_error"
+)
+
+// APIError is implemented by errors that describe themselves as an Error, so handlers can
+// return their own error types and still get a consistent response.
+type APIError interface {
+	error
+	APIError() *Error
+}
+
+// FieldError describes an invalid field of the request.
+type FieldError struct {
+	Field string `json:"field"`
+	// Rule is the validation rule that failed, such as "required".
+	Rule    string `json:"rule"`
+	Message string `json:"message,omitempty"`
+}
+
+// Error is the standard error model, written as an ErrorEnvelope or a Problem.
+type Error struct {
+	Status      int          `json:"-"`
+	Code        string       `json:"code"`
+	Message     string       `json:"message"`
+	Details     any          `json:"details,omitempty"`
+	FieldErrors []FieldError `json:"fieldErrors,omitempty"`
+	TraceID     string       `json:"traceId,omitempty"`
+	// Err is the cause, it is never written to the response.
+	Err error `json:"-"`
+}
+
+func NewError(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return NewError(http.StatusBadRequest, CodeInvalidRequest, message)
+}
+
+func Unauthorized(message string) *Error {
+	return NewError(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return NewError(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return NewError(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return NewError(http.StatusConflict, CodeConflict, message)
+}
+
+// Internal returns a 500 error with a generic message, keeping err as the hidden cause.
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal server error", Err: err}
+}
+
+func ValidationFailed(fieldErrors ...FieldError) *Error {
+	e := NewError(http.StatusBadRequest, CodeValidationFailed, "validation failed")
+	e.FieldErrors = fieldErrors
+	return e
+}
+
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+func (e *Error) WithCause(err error) *Error {
+	e.Err = err
+	return e
+}
+
+func (e *Error) WithTraceID(traceID string) *Error {
+	e.TraceID = traceID
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) APIError() *Error {
+	return e
+}
+
+// FromError converts err to an Error: an APIError describes itself, validator errors from
+// jsonutil.ValidateModel become a validation_failed error with field errors named after the
+// json tags, and any other error becomes Internal. The returned Error is a copy that can be
+// modified.
+func FromError(err error) *Error {
+	var apiErr APIError
+	if errors.As(err, &apiErr) {
+		e := *apiErr.APIError()
+		if e.Status == 0 {
+			e.Status = http.StatusInternalServerError
+		}
+		return &e
+	}
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fieldErrors := make([]FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   fieldErr.Field(),
+				Rule:    fieldErr.Tag(),
+				Message: fieldErr.Error(),
+			})
+		}
+		return ValidationFailed(fieldErrors...).WithCause(err)
+	}
+	return Internal(err)
+}
+
+// ErrorEnvelope is the project error body:
+//
+//	{"error": {"code": "not_found", "message": "user 42 not found", "traceId": "..."}}
+type ErrorEnvelope struct {
+	Error *Error `json:"error"`
+}
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body, extended with the fields of Error.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code,omitempty"`
+	Details  any          `json:"details,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	TraceID  string       `json:"traceId,omitempty"`
+}
+
+// ProblemTypeBaseURL prefixes the error code to build the problem type URI, "about:blank" is
+// used when it is empty.
+var ProblemTypeBaseURL = ""
+
+// NewProblem converts an Error to a Problem.
+func NewProblem(e *Error) *Problem {
+	problemType := "about:blank"
+	if ProblemTypeBaseURL != "" && e.Code != "" {
+		problemType = ProblemTypeBaseURL + e.Code
+	}
+	return &Problem{
+		Type:    problemType,
+		Title:   http.StatusText(e.Status),
+		Status:  e.Status,
+		Detail:  e.Message,
+		Code:    e.Code,
+		Details: e.Details,
+		Errors:  e.FieldErrors,
+		TraceID: e.TraceID,
+	}
+}
+
+// ErrorFormat selects the body written by WriteError.
+type ErrorFormat int
+
+const (
+	// ErrorFormatEnvelope writes an ErrorEnvelope as application/json.
+	ErrorFormatEnvelope ErrorFormat = iota
+	// ErrorFormatProblem writes a Problem as application/problem+json.
+	ErrorFormatProblem
+)
+
+var DefaultErrorFormat = ErrorFormatEnvelope
+
+// WriteError writes err, converted with FromError, in DefaultErrorFormat.
+func WriteError(w http.ResponseWriter, err error) {
+	WriteErrorFormat(w, err, DefaultErrorFormat)
+}
+
+func WriteErrorFormat(w http.ResponseWriter, err error, format ErrorFormat) {
+	e := FromError(err)
+	if format == ErrorFormatProblem {
+		writeProblem(w, NewProblem(e))
+		return
+	}
+	WriteJSONResponse(w, &ErrorEnvelope{Error: e}, e.Status)
+}
+
+// WriteProblem writes err, converted with FromError, as application/problem+json.
+func WriteProblem(w http.ResponseWriter, err error) {
+	WriteErrorFormat(w, err, ErrorFormatProblem)
+}
+
+func writeProblem(w http.ResponseWriter, problem *Problem) {
+	data, err := json.Marshal(problem)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(data)
+}
@@ -0,0 +1,86 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vkviyu/nexus/utils/jsonutil"
+)
+
+type quotaError struct{ limit int }
+
+func (e *quotaError) Error() string { return fmt.Sprintf("quota of %d exceeded", e.limit) }
+
+func (e *quotaError) APIError() *Error {
+	return NewError(http.StatusTooManyRequests, "quota_exceeded", e.Error()).WithDetails(map[string]int{"limit": e.limit})
+}
+
+func TestFromError(t *testing.T) {
+	type model struct {
+		Name string `validate:"required"`
+	}
+	validationErr := jsonutil.ValidateModel(&model{})
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantFields int
+	}{
+		{"api error", fmt.Errorf("wrapped: %w", &quotaError{limit: 3}), http.StatusTooManyRequests, "quota_exceeded", 0},
+		{"constructor", NotFound("user not found"), http.StatusNotFound, CodeNotFound, 0},
+		{"validation", validationErr, http.StatusBadRequest, CodeValidationFailed, 1},
+		{"plain", errors.New("secret"), http.StatusInternalServerError, CodeInternal, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := FromError(tt.err)
+			if e.Status != tt.wantStatus || e.Code != tt.wantCode || len(e.FieldErrors) != tt.wantFields {
+				t.Errorf("unexpected error %+v", e)
+			}
+		})
+	}
+}
+
+func TestFromErrorJSONFieldNames(t *testing.T) {
+	type signup struct {
+		UserName string `json:"user_name,omitempty" validate:"required"`
+		Password string `json:"-" validate:"required"`
+		Email    string `validate:"required"`
+	}
+	e := FromError(jsonutil.ValidateModel(&signup{}))
+	var fields []string
+	for _, fieldErr := range e.FieldErrors {
+		fields = append(fields, fieldErr.Field)
+	}
+	if want := []string{"user_name", "Password", "Email"}; fmt.Sprint(fields) != fmt.Sprint(want) {
+		t.Errorf("expected fields %v, got %v", want, fields)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, errors.New("secret"))
+	var envelope ErrorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("invalid envelope %s: %v", rec.Body, err)
+	}
+	if rec.Code != http.StatusInternalServerError || envelope.Error.Message != "internal server error" {
+		t.Errorf("unexpected response %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteProblem(rec, ValidationFailed(FieldError{Field: "name", Rule: "required"}))
+	if rec.Header().Get("Content-Type") != ProblemContentType || rec.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected problem response %d %v", rec.Code, rec.Header())
+	}
+	var problem Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil || len(problem.Errors) != 1 || problem.Title != "Bad Request" {
+		t.Errorf("unexpected problem %s", rec.Body)
+	}
+}
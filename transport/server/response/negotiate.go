@@ -0,0 +1,185 @@
+package response
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Media types offered by Write, in order of preference.
+const (
+	ContentTypeJSON   = "application/json"
+	ContentTypeYAML   = "application/yaml"
+	ContentTypeText   = "text/plain"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// Encoder encodes a body for a media type.
+type Encoder func(body any) ([]byte, error)
+
+// Encoders maps the media types offered by Write to their encoder; the first entry of
+// EncoderOrder is used when the request has no Accept header.
+var (
+	Encoders = map[string]Encoder{
+		ContentTypeJSON: json.Marshal,
+		ContentTypeYAML: yaml.Marshal,
+		ContentTypeText: encodeText,
+	}
+	EncoderOrder = []string{ContentTypeJSON, ContentTypeYAML, ContentTypeText}
+)
+
+// mediaTypeAliases maps alternative names to the media types of Encoders.
+var mediaTypeAliases = map[string]string{
+	"application/x-yaml": ContentTypeYAML,
+	"text/yaml":          ContentTypeYAML,
+	"text/x-yaml":        ContentTypeYAML,
+}
+
+// GzipMinSize is the body size from which Write compresses when the client accepts gzip.
+var GzipMinSize = 1024
+
+func encodeText(body any) ([]byte, error) {
+	switch v := body.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case fmt.Stringer:
+		return []byte(v.String()), nil
+	case error:
+		return []byte(v.Error()), nil
+	}
+	return []byte(fmt.Sprint(body)), nil
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "" {
+			continue
+		}
+		if alias, ok := mediaTypeAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+func (a acceptRange) matches(offer string) bool {
+	if a.mediaType == "*/*" || a.mediaType == offer {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(a.mediaType, "/*")
+	return ok && strings.HasPrefix(offer, prefix+"/")
+}
+
+// Negotiate returns the offer that best matches the Accept header, the first offer when
+// accept is empty, or "" when no offer is acceptable.
+func Negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// 以最具体的匹配范围的 q 值为准，q=0 表示拒绝
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if !r.matches(offer) {
+				continue
+			}
+			if s := 2 - strings.Count(r.mediaType, "*"); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// AcceptsGzip reports whether the client accepts a gzip encoded response. An explicit gzip
+// entry takes precedence over "*", and q=0 refuses the encoding.
+func AcceptsGzip(r *http.Request) bool {
+	gzipQ, wildcardQ := -1.0, -1.0
+	for _, encoding := range parseAccept(r.Header.Get("Accept-Encoding")) {
+		switch encoding.mediaType {
+		case "gzip", "x-gzip":
+			gzipQ = encoding.q
+		case "*":
+			wildcardQ = encoding.q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return wildcardQ > 0
+}
+
+// Write encodes body in the format the request accepts among EncoderOrder, JSON by default,
+// and compresses it when the client accepts gzip and it is at least GzipMinSize bytes.
+// It answers 406 when no format is acceptable.
+func Write(w http.ResponseWriter, r *http.Request, body any, statusCode int) {
+	contentType := Negotiate(r.Header.Get("Accept"), EncoderOrder...)
+	if contentType == "" {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return
+	}
+	data, err := Encoders[contentType](body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	header := w.Header()
+	if strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	header.Set("Content-Type", contentType)
+	header.Add("Vary", "Accept")
+	if len(data) >= GzipMinSize && AcceptsGzip(r) && header.Get("Content-Encoding") == "" {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(data)
+		gz.Close()
+		data = buf.Bytes()
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+	}
+	header.Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(statusCode)
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
+
+// WriteNegotiatedOK writes body with status 200, see Write.
+func WriteNegotiatedOK(w http.ResponseWriter, r *http.Request, body any) {
+	Write(w, r, body, http.StatusOK)
+}
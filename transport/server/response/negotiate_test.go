@@ -0,0 +1,147 @@
+package response
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ContentTypeJSON},
+		{"application/yaml", ContentTypeYAML},
+		{"text/x-yaml", ContentTypeYAML},
+		{"text/*;q=0.5, application/json;q=0.4", ContentTypeText},
+		{"*/*;q=0.1, application/yaml", ContentTypeYAML},
+		{"application/json;q=0, */*", ContentTypeYAML},
+		{"image/png", ""},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.accept, EncoderOrder...); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"*", true},
+		{"gzip;q=0", false},
+		{"*, gzip;q=0", false},
+		{"gzip;q=0.0, *;q=1", false},
+		{"*;q=0, gzip", true},
+		{"identity", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		if got := AcceptsGzip(r); got != tt.want {
+			t.Errorf("AcceptsGzip(%q) = %v, want %v", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	body := map[string]string{"name": "nexus"}
+	tests := []struct {
+		accept     string
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{"", http.StatusOK, ContentTypeJSON, `{"name":"nexus"}`},
+		{"application/x-yaml", http.StatusOK, ContentTypeYAML, "name: nexus\n"},
+		{"text/plain", http.StatusOK, "text/plain; charset=utf-8", "map[name:nexus]"},
+		{"image/png", http.StatusNotAcceptable, "text/plain; charset=utf-8", "Not Acceptable\n"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", tt.accept)
+		Write(rec, req, body, http.StatusOK)
+		if rec.Code != tt.wantStatus || rec.Header().Get("Content-Type") != tt.wantType || rec.Body.String() != tt.wantBody {
+			t.Errorf("%q: got %d %q %q", tt.accept, rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+	}
+
+	large := strings.Repeat("x", GzipMinSize)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/plain")
+	req.Header.Set("Accept-Encoding", "gzip")
+	Write(rec, req, large, http.StatusOK)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got %v", rec.Header())
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	if data, _ := io.ReadAll(reader); string(data) != large {
+		t.Errorf("unexpected body after decompression")
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteNDJSON(w, slices.Values([]map[string]int{{"n": 1}, {"n": 2}}))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != ContentTypeNDJSON {
+		t.Errorf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if strings.Join(lines, "|") != `{"n":1}|{"n":2}` {
+		t.Errorf("unexpected lines %q", lines)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	WriteAttachment(rec, req, path, "")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "234" {
+		t.Errorf("expected partial content 234, got %d %q", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Content-Range") != "bytes 2-4/10" || rec.Header().Get("Content-Disposition") != `attachment; filename=report.txt` {
+		t.Errorf("unexpected headers %v", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	WriteFile(rec, httptest.NewRequest(http.MethodGet, "/", nil), filepath.Dir(path))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a directory, got %d", rec.Code)
+	}
+}
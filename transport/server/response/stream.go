@@ -0,0 +1,98 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"iter"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// NDJSONWriter streams values as newline-delimited JSON, flushing after each one so clients
+// receive them as they are produced.
+type NDJSONWriter struct {
+	w          http.ResponseWriter
+	encoder    *json.Encoder
+	controller *http.ResponseController
+	started    bool
+}
+
+func NewNDJSONWriter(w http.ResponseWriter) *NDJSONWriter {
+	return &NDJSONWriter{
+		w:          w,
+		encoder:    json.NewEncoder(w),
+		controller: http.NewResponseController(w),
+	}
+}
+
+// Write encodes v as one line. The first call writes the headers with status 200.
+func (n *NDJSONWriter) Write(v any) error {
+	if !n.started {
+		n.started = true
+		n.w.Header().Set("Content-Type", ContentTypeNDJSON)
+		n.w.WriteHeader(http.StatusOK)
+	}
+	if err := n.encoder.Encode(v); err != nil {
+		return err
+	}
+	if err := n.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// WriteNDJSON streams every value of seq as NDJSON without buffering the whole body.
+// It stops at the first write error, for example when the client goes away.
+func WriteNDJSON[T any](w http.ResponseWriter, seq iter.Seq[T]) error {
+	writer := NewNDJSONWriter(w)
+	for v := range seq {
+		if err := writer.Write(v); err != nil {
+			return err
+		}
+	}
+	if !writer.started {
+		w.Header().Set("Content-Type", ContentTypeNDJSON)
+		w.WriteHeader(http.StatusOK)
+	}
+	return nil
+}
+
+// WriteFile serves the file at path with http.ServeContent, which handles Range requests,
+// conditional requests and the Content-Type. Missing files and directories answer 404.
+func WriteFile(w http.ResponseWriter, r *http.Request, path string) {
+	serveFile(w, r, path, "")
+}
+
+// WriteAttachment is WriteFile with a Content-Disposition that makes browsers download the file
+// as filename, the base name of path when empty.
+func WriteAttachment(w http.ResponseWriter, r *http.Request, path, filename string) {
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	serveFile(w, r, path, filename)
+}
+
+func serveFile(w http.ResponseWriter, r *http.Request, path, attachment string) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	if attachment != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment}))
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
@@ -0,0 +1,446 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vkviyu/nexus/transport/auth"
+	"github.com/vkviyu/nexus/transport/server/websocket"
+)
+
+// The message model is shared with the websocket package, so one Manager.SendMessage call
+// reaches WebSocket and SSE clients alike.
+type (
+	EndpointPath = websocket.EndpointPath
+	ConnId       = websocket.ConnId
+	Message      = websocket.Message
+)
+
+// BinaryEventType is the event name of binary messages, whose data is base64 encoded.
+// Text messages use the default "message" event and need no event line.
+var BinaryEventType = "binary"
+
+var (
+	// DefaultKeepAliveInterval is the interval between keep-alive comments on idle streams.
+	DefaultKeepAliveInterval = 15 * time.Second
+	// DefaultReplayBufferSize is the number of recent events kept for Last-Event-ID replay.
+	DefaultReplayBufferSize = 256
+	// DefaultConnBufferSize is the number of events queued per connection; a connection that
+	// falls further behind is closed and replays the missed events when it reconnects.
+	DefaultConnBufferSize = 64
+)
+
+// LastEventIdQuery is the query parameter read when the Last-Event-ID header is absent,
+// for clients that cannot set headers on reconnect.
+var LastEventIdQuery = "lastEventId"
+
+// event is a message that was sent on the endpoint, kept in the replay buffer.
+type event struct {
+	id      uint64
+	connIds []ConnId // empty for broadcasts
+	data    []byte   // the encoded event, ready to be written
+}
+
+func (ev *event) targets(connId ConnId) bool {
+	if len(ev.connIds) == 0 {
+		return true
+	}
+	for _, id := range ev.connIds {
+		if id == connId {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn is a client connected to an Endpoint.
+type Conn struct {
+	ConnId      ConnId
+	RemoteAddr  string
+	ConnectedAt time.Time
+	Principal   *auth.Principal
+
+	events   chan *event
+	overflow chan struct{}
+	once     sync.Once
+}
+
+// drop makes the stream return, used when the connection cannot keep up.
+func (c *Conn) drop() {
+	c.once.Do(func() {
+		close(c.overflow)
+	})
+}
+
+// Endpoint is an http.Handler that streams messages to clients as Server-Sent Events.
+// Messages are one-way, from server to client; register it on a websocket.Manager with
+// AddMessageEndpoint to reach it together with WebSocket endpoints.
+type Endpoint struct {
+	EndpointPath  EndpointPath
+	AuthFunc      auth.AuthFunc
+	Authenticator auth.Authenticator
+	AuthFailFunc  auth.AuthFailFunc
+	// KeepAliveInterval is the interval between keep-alive comments, disabled if negative.
+	KeepAliveInterval time.Duration
+	// ReplayBufferSize is the number of recent events kept for Last-Event-ID replay, replay is
+	// disabled if negative.
+	ReplayBufferSize int
+	// Retry is sent to clients as the reconnection delay when positive.
+	Retry time.Duration
+
+	conns       map[ConnId]*Conn
+	buffer      []*event
+	lastId      uint64
+	mu          sync.RWMutex
+	closed      bool
+	done        chan struct{}
+	streams     sync.WaitGroup
+	total       atomic.Uint64
+	messagesOut atomic.Uint64
+	bytesOut    atomic.Uint64
+	errors      atomic.Uint64
+}
+
+func NewEndpoint(path EndpointPath, options ...EndpointOption) *Endpoint {
+	endpoint := &Endpoint{
+		EndpointPath: path,
+	}
+	endpoint.SetOptions(options...)
+	endpoint.applyDefaultsIfNil()
+	return endpoint
+}
+
+type EndpointOption func(*Endpoint)
+
+func (e *Endpoint) SetOptions(options ...EndpointOption) {
+	for _, option := range options {
+		option(e)
+	}
+}
+
+func WithAuthFunc(authFunc auth.AuthFunc) EndpointOption {
+	return func(e *Endpoint) {
+		e.AuthFunc = authFunc
+	}
+}
+
+// WithAuthenticator authenticates clients with an auth.Authenticator, which takes precedence
+// over the AuthFunc. The principal ID is the ConnId and the principal is Conn.Principal.
+func WithAuthenticator(authenticator auth.Authenticator) EndpointOption {
+	return func(e *Endpoint) {
+		e.Authenticator = authenticator
+	}
+}
+
+func WithAuthFailFunc(authFailFunc auth.AuthFailFunc) EndpointOption {
+	return func(e *Endpoint) {
+		e.AuthFailFunc = authFailFunc
+	}
+}
+
+func WithKeepAliveInterval(interval time.Duration) EndpointOption {
+	return func(e *Endpoint) {
+		e.KeepAliveInterval = interval
+	}
+}
+
+func WithReplayBufferSize(size int) EndpointOption {
+	return func(e *Endpoint) {
+		e.ReplayBufferSize = size
+	}
+}
+
+func WithRetry(retry time.Duration) EndpointOption {
+	return func(e *Endpoint) {
+		e.Retry = retry
+	}
+}
+
+func (e *Endpoint) applyDefaultsIfNil() {
+	if e.AuthFunc == nil {
+		e.AuthFunc = auth.DefaultAuthFunc
+	}
+	if e.AuthFailFunc == nil {
+		e.AuthFailFunc = auth.DefaultAuthFailFunc
+	}
+	if e.KeepAliveInterval == 0 {
+		e.KeepAliveInterval = DefaultKeepAliveInterval
+	}
+	if e.ReplayBufferSize == 0 {
+		e.ReplayBufferSize = DefaultReplayBufferSize
+	}
+	if e.conns == nil {
+		e.conns = make(map[ConnId]*Conn)
+	}
+	if e.done == nil {
+		e.done = make(chan struct{})
+	}
+}
+
+func (e *Endpoint) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	authenticator := e.Authenticator
+	if authenticator == nil {
+		authenticator = auth.FromAuthFunc(e.AuthFunc)
+	}
+	principal, err := authenticator.Authenticate(r)
+	if err != nil || principal == nil {
+		e.AuthFailFunc(rw, r)
+		return
+	}
+	connId := principal.ID
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get(LastEventIdQuery)
+	}
+	var lastId uint64
+	if lastEventId != "" {
+		var err error
+		if lastId, err = strconv.ParseUint(lastEventId, 10, 64); err != nil {
+			http.Error(rw, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn := &Conn{
+		ConnId:      connId,
+		RemoteAddr:  r.RemoteAddr,
+		ConnectedAt: time.Now(),
+		Principal:   principal,
+		events:      make(chan *event, DefaultConnBufferSize),
+		overflow:    make(chan struct{}),
+	}
+	// Registering and collecting the replay under one lock means every event is either
+	// replayed or queued on the connection, never both.
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		http.Error(rw, "SSE endpoint is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	var replay []*event
+	if lastEventId != "" {
+		for _, ev := range e.buffer {
+			if ev.id > lastId && ev.targets(connId) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	if old, ok := e.conns[connId]; ok {
+		old.drop()
+	}
+	e.conns[connId] = conn
+	e.streams.Add(1)
+	e.mu.Unlock()
+	e.total.Add(1)
+	defer e.streams.Done()
+	defer func() {
+		e.mu.Lock()
+		if e.conns[connId] == conn {
+			delete(e.conns, connId)
+		}
+		e.mu.Unlock()
+	}()
+
+	header := rw.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	if e.Retry > 0 {
+		fmt.Fprintf(rw, "retry: %d\n\n", e.Retry.Milliseconds())
+	}
+	for _, ev := range replay {
+		if !e.write(rw, ev) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	var keepAlive <-chan time.Time
+	if e.KeepAliveInterval > 0 {
+		ticker := time.NewTicker(e.KeepAliveInterval)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+	for {
+		select {
+		case ev := <-conn.events:
+			if !e.write(rw, ev) {
+				return
+			}
+		case <-keepAlive:
+			if _, err := rw.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+		case <-conn.overflow:
+			return
+		case <-r.Context().Done():
+			return
+		case <-e.done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// write writes an encoded event and reports whether the stream is still usable.
+func (e *Endpoint) write(rw http.ResponseWriter, ev *event) bool {
+	n, err := rw.Write(ev.data)
+	if err != nil {
+		e.errors.Add(1)
+		return false
+	}
+	e.messagesOut.Add(1)
+	e.bytesOut.Add(uint64(n))
+	return true
+}
+
+var lineEndings = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// encodeEvent formats a message as an event; binary messages are base64 encoded.
+func encodeEvent(id uint64, msg *Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "id: %d\n", id)
+	data := msg.Message
+	if msg.MessageType == websocket.BinaryMessage {
+		buf.WriteString("event: " + BinaryEventType + "\n")
+		data = []byte(base64.StdEncoding.EncodeToString(data))
+	}
+	// 多行文本需要拆分成多个 data 字段；\r\n、\r 与 \n 都是 SSE 的行结束符，
+	// 单独的 \r 若原样输出会注入 event/id 等字段
+	for _, line := range strings.Split(lineEndings.Replace(string(data)), "\n") {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// SendMessage sends msg with the semantics of websocket.Endpoint.SendMessage. Every message is
+// kept in the replay buffer, so a client targeted while disconnected still receives it when
+// it reconnects with Last-Event-ID, although a ConnNotFoundError is reported.
+func (e *Endpoint) SendMessage(msg *Message) error {
+	if msg.MessageType == 0 {
+		msg.MessageType = websocket.DefaultMessageType
+	}
+	e.mu.Lock()
+	e.lastId++
+	ev := &event{
+		id:      e.lastId,
+		connIds: msg.ConnIds,
+		data:    encodeEvent(e.lastId, msg),
+	}
+	if e.ReplayBufferSize > 0 {
+		if len(e.buffer) >= e.ReplayBufferSize {
+			e.buffer = append(e.buffer[:0], e.buffer[len(e.buffer)-e.ReplayBufferSize+1:]...)
+		}
+		e.buffer = append(e.buffer, ev)
+	}
+	var errs []error
+	if len(msg.ConnIds) == 0 {
+		for _, conn := range e.conns {
+			e.enqueue(conn, ev)
+		}
+	} else {
+		for _, connId := range msg.ConnIds {
+			conn, ok := e.conns[connId]
+			if !ok {
+				errs = append(errs, &websocket.ConnNotFoundError{
+					EndpointPath: e.EndpointPath,
+					ConnId:       connId,
+				})
+				continue
+			}
+			e.enqueue(conn, ev)
+		}
+	}
+	e.mu.Unlock()
+	if len(errs) > 0 {
+		return &websocket.MessageSendError{
+			EndpointPath: e.EndpointPath,
+			Errors:       errs,
+		}
+	}
+	return nil
+}
+
+// enqueue queues an event without blocking, dropping connections that fell too far behind.
+func (e *Endpoint) enqueue(conn *Conn, ev *event) {
+	select {
+	case conn.events <- ev:
+	default:
+		e.errors.Add(1)
+		conn.drop()
+	}
+}
+
+// Close stops accepting streams and ends the active ones, waiting for them until ctx is done.
+func (e *Endpoint) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.done)
+	}
+	e.mu.Unlock()
+
+	streamsDone := make(chan struct{})
+	go func() {
+		e.streams.Wait()
+		close(streamsDone)
+	}()
+	select {
+	case <-streamsDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Endpoint) GetEndpointPath() EndpointPath {
+	return e.EndpointPath
+}
+
+func (e *Endpoint) GetConn(connId ConnId) *Conn {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.conns[connId]
+}
+
+func (e *Endpoint) HasConn(connId ConnId) bool {
+	return e.GetConn(connId) != nil
+}
+
+func (e *Endpoint) GetConnCount() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.conns)
+}
+
+// Stats returns a snapshot of the endpoint's counters; the In counters are always zero.
+func (e *Endpoint) Stats() websocket.EndpointStats {
+	return websocket.EndpointStats{
+		EndpointPath:      e.EndpointPath,
+		ActiveConnections: e.GetConnCount(),
+		TotalConnections:  e.total.Load(),
+		MessagesOut:       e.messagesOut.Load(),
+		BytesOut:          e.bytesOut.Load(),
+		Errors:            e.errors.Load(),
+	}
+}
+
+var _ websocket.MessageEndpoint = (*Endpoint)(nil)
@@ -0,0 +1,149 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vkviyu/nexus/transport/server/websocket"
+)
+
+// readEvent reads the next event from an SSE stream, skipping comments.
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(lines) > 0 {
+				return lines
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+}
+
+func openStream(t *testing.T, url string, lastEventId string) *bufio.Reader {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func waitForConn(t *testing.T, ep *Endpoint, connId ConnId) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !ep.HasConn(connId) {
+		if time.Now().After(deadline) {
+			t.Fatalf("connection %s not registered", connId)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEncodeEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"text", Message{MessageType: websocket.TextMessage, Message: []byte("hi")}, "id: 1\ndata: hi\n\n"},
+		{"multiline", Message{MessageType: websocket.TextMessage, Message: []byte("a\r\nb")}, "id: 1\ndata: a\ndata: b\n\n"},
+		{"lone cr", Message{MessageType: websocket.TextMessage, Message: []byte("x\revent: admin\rid: 999")}, "id: 1\ndata: x\ndata: event: admin\ndata: id: 999\n\n"},
+		{"binary", Message{MessageType: websocket.BinaryMessage, Message: []byte{0xff, 0x00}}, "id: 1\nevent: binary\ndata: /wA=\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(encodeEvent(1, &tt.msg)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerBroadcastAndReplay(t *testing.T) {
+	ep := NewEndpoint("/events", WithAuthFunc(func(r *http.Request) (bool, string) {
+		return true, "alice"
+	}))
+	manager := websocket.NewManager()
+	manager.AddEndpoint(websocket.NewEndpoint("/ws"))
+	manager.AddMessageEndpoint(ep)
+	server := httptest.NewServer(ep)
+	t.Cleanup(server.Close)
+
+	reader := openStream(t, server.URL, "")
+	waitForConn(t, ep, "alice")
+	if err := manager.SendMessage(&websocket.EndpointMessage{Message: Message{Message: []byte("first")}}); err != nil {
+		t.Fatalf("broadcast failed: %v", err)
+	}
+	if lines := readEvent(t, reader); strings.Join(lines, "|") != "id: 1|data: first" {
+		t.Fatalf("unexpected event %q", lines)
+	}
+
+	err := manager.SendMessage(&websocket.EndpointMessage{
+		Message:      Message{Message: []byte("missed"), ConnIds: []ConnId{"alice", "bob"}},
+		EndpointPath: "/events",
+	})
+	if err == nil {
+		t.Error("expected ConnNotFoundError for bob")
+	}
+	if lines := readEvent(t, reader); strings.Join(lines, "|") != "id: 2|data: missed" {
+		t.Fatalf("unexpected event %q", lines)
+	}
+
+	// A reconnecting client receives the events after its Last-Event-ID.
+	reader = openStream(t, server.URL, "1")
+	if lines := readEvent(t, reader); strings.Join(lines, "|") != "id: 2|data: missed" {
+		t.Fatalf("unexpected replay %q", lines)
+	}
+	if stats := ep.Stats(); stats.TotalConnections != 2 || stats.MessagesOut != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := manager.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after shutdown, got %d", resp.StatusCode)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	ep := NewEndpoint("/events", WithKeepAliveInterval(10*time.Millisecond))
+	server := httptest.NewServer(ep)
+	t.Cleanup(server.Close)
+
+	reader := openStream(t, server.URL, "")
+	line, err := reader.ReadString('\n')
+	if err != nil || line != ": keep-alive\n" {
+		t.Fatalf("expected keep-alive comment, got %q %v", line, err)
+	}
+}
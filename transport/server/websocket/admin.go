@@ -0,0 +1,159 @@
+package websocket
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/vkviyu/nexus/transport/server/response"
+)
+
+// NewAdminHandler returns an http.Handler to inspect and operate the endpoints of a manager:
+//
+//	GET  /endpoints                                    stats of every endpoint
+//	GET  /connections?endpoint=/ws                     stats of the endpoint's connections
+//	POST /kick?endpoint=/ws&conn=ID[&code=N&reason=R]  close a connection (default 1008)
+//	POST /send?endpoint=/ws[&conn=ID]                  send the request body as a text message,
+//	                                                   broadcast when conn is omitted
+//
+// Bodies of /send are limited to the ReadLimit of the endpoint (413 beyond it), and close
+// codes of /kick must be valid per RFC 6455.
+//
+// Mount it under a prefix behind your own authentication, for example:
+//
+//	mux.Handle("/admin/ws/", http.StripPrefix("/admin/ws", websocket.NewAdminHandler(manager)))
+func NewAdminHandler(manager *Manager) http.Handler {
+	admin := &adminHandler{manager: manager}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /endpoints", admin.endpoints)
+	mux.HandleFunc("GET /connections", admin.connections)
+	mux.HandleFunc("POST /kick", admin.kick)
+	mux.HandleFunc("POST /send", admin.send)
+	return mux
+}
+
+type adminHandler struct {
+	manager *Manager
+}
+
+type adminError struct {
+	Error string `json:"error"`
+}
+
+func (h *adminHandler) endpoint(w http.ResponseWriter, r *http.Request) *Endpoint {
+	path := r.URL.Query().Get("endpoint")
+	if path == "" {
+		response.WriteBadRequest(w, adminError{Error: "missing endpoint parameter"})
+		return nil
+	}
+	endpoint := h.manager.GetEndpoint(path)
+	if endpoint == nil {
+		response.WriteNotFound(w, adminError{Error: (&EndpointNotFoundError{EndpointPath: path}).Error()})
+		return nil
+	}
+	return endpoint
+}
+
+func (h *adminHandler) endpoints(w http.ResponseWriter, r *http.Request) {
+	response.WriteOK(w, h.manager.Stats())
+}
+
+func (h *adminHandler) connections(w http.ResponseWriter, r *http.Request) {
+	endpoint := h.endpoint(w, r)
+	if endpoint == nil {
+		return
+	}
+	response.WriteOK(w, endpoint.ConnStats())
+}
+
+func (h *adminHandler) kick(w http.ResponseWriter, r *http.Request) {
+	endpoint := h.endpoint(w, r)
+	if endpoint == nil {
+		return
+	}
+	query := r.URL.Query()
+	code := ClosePolicyViolation
+	if rawCode := query.Get("code"); rawCode != "" {
+		var err error
+		if code, err = strconv.Atoi(rawCode); err != nil {
+			response.WriteBadRequest(w, adminError{Error: "invalid code parameter"})
+			return
+		}
+	}
+	if !validCloseCode(code) {
+		response.WriteBadRequest(w, adminError{Error: "invalid close code " + strconv.Itoa(code)})
+		return
+	}
+	if err := endpoint.Kick(query.Get("conn"), code, query.Get("reason")); err != nil {
+		var notFound *ConnNotFoundError
+		if errors.As(err, &notFound) {
+			response.WriteNotFound(w, adminError{Error: err.Error()})
+		} else {
+			response.WriteBadGateway(w, adminError{Error: err.Error()})
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *adminHandler) send(w http.ResponseWriter, r *http.Request) {
+	endpoint := h.endpoint(w, r)
+	if endpoint == nil {
+		return
+	}
+	limit := endpoint.ReadLimit
+	if limit <= 0 {
+		limit = DefaultReadLimit
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.WriteJSONResponse(w, adminError{Error: err.Error()}, http.StatusRequestEntityTooLarge)
+		} else {
+			response.WriteBadRequest(w, adminError{Error: err.Error()})
+		}
+		return
+	}
+	msg := &Message{MessageType: TextMessage, Message: body}
+	if connId := r.URL.Query().Get("conn"); connId != "" {
+		msg.ConnIds = []ConnId{connId}
+	}
+	if err := endpoint.SendMessage(msg); err != nil {
+		if connNotFound(err) {
+			response.WriteNotFound(w, adminError{Error: err.Error()})
+		} else {
+			response.WriteBadGateway(w, adminError{Error: err.Error()})
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// connNotFound reports whether every error of a MessageSendError is a ConnNotFoundError.
+func connNotFound(err error) bool {
+	var sendErr *MessageSendError
+	if !errors.As(err, &sendErr) || len(sendErr.Errors) == 0 {
+		return false
+	}
+	for _, err := range sendErr.Errors {
+		var notFound *ConnNotFoundError
+		if !errors.As(err, &notFound) {
+			return false
+		}
+	}
+	return true
+}
+
+// validCloseCode reports whether code may be sent in a close frame (RFC 6455, section 7.4):
+// 1004-1006 and 1015 are reserved, 1016-2999 are reserved for the protocol.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1014:
+		return false
+	}
+	return code < 1004 || code > 1006
+}
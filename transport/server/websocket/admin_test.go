@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAdminHandler(t *testing.T) {
+	ep := NewEndpoint("/ws", WithAuthFunc(func(r *http.Request) (bool, string) {
+		return true, "alice"
+	}))
+	manager := NewManager()
+	manager.AddEndpoint(ep)
+	admin := NewAdminHandler(manager)
+
+	conn := dialTestEndpoint(t, ep, "")
+	waitForConn(t, ep, "alice")
+
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/send?endpoint=/ws&conn=alice", strings.NewReader("hello")))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("send: expected 204, got %d %s", rec.Code, rec.Body)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "hello" {
+		t.Fatalf("expected hello, got %q %v", data, err)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/endpoints", nil))
+	var stats []EndpointStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid stats %s: %v", rec.Body, err)
+	}
+	if len(stats) != 1 || stats[0].ActiveConnections != 1 || stats[0].MessagesOut != 1 || stats[0].BytesOut != 5 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/connections?endpoint=/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("connections of unknown endpoint: expected 404, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/send?endpoint=/ws&conn=nobody", strings.NewReader("hello")))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("send to unknown connection: expected 404, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/send?endpoint=/ws", strings.NewReader(strings.Repeat("x", int(DefaultReadLimit)+1))))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("send oversized body: expected 413, got %d", rec.Code)
+	}
+
+	for _, code := range []string{"999", "1005", "1006", "1015", "2000", "5000"} {
+		rec = httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/kick?endpoint=/ws&conn=alice&code="+code, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("kick with code %s: expected 400, got %d", code, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/kick?endpoint=/ws&conn=bob", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("kick unknown connection: expected 404, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/kick?endpoint=/ws&conn=alice&reason=bye", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("kick: expected 204, got %d %s", rec.Code, rec.Body)
+	}
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != ClosePolicyViolation || closeErr.Text != "bye" {
+		t.Errorf("expected close frame 1008 bye, got %v", err)
+	}
+
+	if count := manager.GetConnCount("/unknown"); count != 0 {
+		t.Errorf("expected 0 connections for unknown endpoint, got %d", count)
+	}
+}
@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// BrokerMessage is an EndpointMessage travelling between nodes through a Broker.
+// NodeId identifies the publishing node so that it can skip its own messages.
+type BrokerMessage struct {
+	NodeId string
+	// DeliveryId is the ID under which the publishing node stored a Reliable message, see
+	// OfflineStore. The node holding a target connection stores and delivers it with this ID.
+	DeliveryId string
+	EndpointMessage
+}
+
+// BrokerHandler is called for every message received from a Broker.
+type BrokerHandler func(msg *BrokerMessage)
+
+// Broker fans messages out to every node of a cluster.
+// Manager publishes messages it cannot fully deliver locally, and delivers
+// messages received from other nodes to its own connections.
+type Broker interface {
+	// Publish sends the message to all subscribers, including those of other nodes.
+	Publish(ctx context.Context, msg *BrokerMessage) error
+	// Subscribe registers handler until ctx is done. It must not block.
+	Subscribe(ctx context.Context, handler BrokerHandler) error
+	// Close releases the broker's resources.
+	Close() error
+}
+
+// MemoryBroker is an in-process Broker.
+// Managers sharing one MemoryBroker behave like nodes of a cluster, which is useful for tests.
+type MemoryBroker struct {
+	handlers map[int]BrokerHandler
+	nextId   int
+	mu       sync.RWMutex
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		handlers: make(map[int]BrokerHandler),
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msg *BrokerMessage) error {
+	b.mu.RLock()
+	handlers := make([]BrokerHandler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, handler BrokerHandler) error {
+	b.mu.Lock()
+	id := b.nextId
+	b.nextId++
+	b.handlers[id] = handler
+	b.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}()
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clear(b.handlers)
+	return nil
+}
@@ -0,0 +1,409 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vkviyu/nexus/transport/auth"
+	"github.com/vkviyu/nexus/utils/backoffutil"
+)
+
+// DefaultHubQueueSize is the number of messages a BrokerHub buffers per node.
+var DefaultHubQueueSize = 256
+
+// DefaultHubWriteTimeout bounds the time a BrokerHub spends writing one message to a node.
+var DefaultHubWriteTimeout = 5 * time.Second
+
+// ErrBrokerDisconnected is returned by HubBroker.Publish while the hub is unreachable.
+var ErrBrokerDisconnected = errors.New("broker: disconnected from hub")
+
+// BrokerHub is a reference relay for HubBroker.
+// Every node connects to the hub over WebSocket and the hub forwards each published
+// message to all other nodes. Run it as a standalone service, or in-process as a local
+// stand-in for a real message bus:
+//
+//	hub := websocket.NewBrokerHub(websocket.WithHubAuthFunc(nodeAuth))
+//	http.Handle("/broker", hub)
+//
+//	broker, err := websocket.DialHubBroker(ctx, "ws://127.0.0.1:9000/broker", header)
+//	manager := websocket.NewManager(websocket.WithBroker(broker))
+//
+// Every node has its own queue of QueueSize messages, written with a WriteTimeout; a node
+// that falls behind or fails a write is disconnected, so one slow node cannot stall the others.
+type BrokerHub struct {
+	UpgradeFunc UpgraderFunc
+	// Authenticator, if set, authenticates nodes before the upgrade. Without it any client
+	// that reaches the hub can publish to every node.
+	Authenticator auth.Authenticator
+	AuthFailFunc  auth.AuthFailFunc
+	QueueSize     int
+	WriteTimeout  time.Duration
+
+	peers map[*hubPeer]struct{}
+	mu    sync.RWMutex
+}
+
+type BrokerHubOption func(*BrokerHub)
+
+// WithHubAuthenticator authenticates the nodes connecting to the hub.
+func WithHubAuthenticator(authenticator auth.Authenticator) BrokerHubOption {
+	return func(h *BrokerHub) {
+		h.Authenticator = authenticator
+	}
+}
+
+// WithHubAuthFunc authenticates the nodes connecting to the hub with an auth.AuthFunc.
+func WithHubAuthFunc(authFunc auth.AuthFunc) BrokerHubOption {
+	return func(h *BrokerHub) {
+		h.Authenticator = auth.FromAuthFunc(authFunc)
+	}
+}
+
+func WithHubAuthFailFunc(authFailFunc auth.AuthFailFunc) BrokerHubOption {
+	return func(h *BrokerHub) {
+		h.AuthFailFunc = authFailFunc
+	}
+}
+
+// WithHubQueue sets the per-node queue size and write timeout of the hub.
+func WithHubQueue(size int, writeTimeout time.Duration) BrokerHubOption {
+	return func(h *BrokerHub) {
+		h.QueueSize = size
+		h.WriteTimeout = writeTimeout
+	}
+}
+
+func NewBrokerHub(options ...BrokerHubOption) *BrokerHub {
+	h := &BrokerHub{
+		UpgradeFunc: DefaultUpgradeFunc,
+		peers:       make(map[*hubPeer]struct{}),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	if h.AuthFailFunc == nil {
+		h.AuthFailFunc = auth.DefaultAuthFailFunc
+	}
+	if h.QueueSize <= 0 {
+		h.QueueSize = DefaultHubQueueSize
+	}
+	if h.WriteTimeout <= 0 {
+		h.WriteTimeout = DefaultHubWriteTimeout
+	}
+	return h
+}
+
+type hubFrame struct {
+	messageType int
+	data        []byte
+}
+
+// hubPeer is a node connected to the hub with its send queue.
+type hubPeer struct {
+	conn      *WebSocketConn
+	send      chan hubFrame
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (p *hubPeer) close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.conn.Close()
+	})
+}
+
+func (p *hubPeer) writeLoop(writeTimeout time.Duration) {
+	for {
+		select {
+		case frame := <-p.send:
+			p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := p.conn.WriteMessage(frame.messageType, frame.data); err != nil {
+				p.close()
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (h *BrokerHub) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if h.Authenticator != nil {
+		if principal, err := h.Authenticator.Authenticate(r); err != nil || principal == nil {
+			h.AuthFailFunc(rw, r)
+			return
+		}
+	}
+	conn, err := h.UpgradeFunc(rw, r)
+	if err != nil {
+		DefaultUpgradeFailFunc(rw, r)
+		return
+	}
+	peer := &hubPeer{
+		conn: conn,
+		send: make(chan hubFrame, h.QueueSize),
+		done: make(chan struct{}),
+	}
+	h.mu.Lock()
+	h.peers[peer] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.peers, peer)
+		h.mu.Unlock()
+		peer.close()
+	}()
+	go peer.writeLoop(h.WriteTimeout)
+
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		h.mu.RLock()
+		peers := make([]*hubPeer, 0, len(h.peers))
+		for other := range h.peers {
+			if other != peer {
+				peers = append(peers, other)
+			}
+		}
+		h.mu.RUnlock()
+		frame := hubFrame{messageType: messageType, data: message}
+		for _, other := range peers {
+			select {
+			case other.send <- frame:
+			default:
+				// 队列已满，断开落后的节点，由其重连
+				other.close()
+			}
+		}
+	}
+}
+
+// HubBroker is a Broker connected to a BrokerHub. When the connection fails, the failure
+// is reported to ReadErrorFunc and the broker reconnects with Backoff until it is closed;
+// Publish returns ErrBrokerDisconnected in the meantime. Messages published by other nodes
+// while disconnected are lost.
+type HubBroker struct {
+	url      string
+	header   http.Header
+	conn     *SafeConn
+	connMu   sync.RWMutex
+	handlers map[int]BrokerHandler
+	nextId   int
+	mu       sync.RWMutex
+	done     chan struct{}
+	once     sync.Once
+
+	// ReadErrorFunc is called when the connection to the hub fails, and for every failed
+	// reconnection attempt.
+	ReadErrorFunc ReadErrorFunc
+	// Backoff controls the delay between reconnection attempts.
+	Backoff backoffutil.Backoff
+	// WriteTimeout bounds a Publish, DefaultHubWriteTimeout if zero. The deadline of the
+	// Publish ctx applies when it is earlier. A failed write drops the connection to the hub.
+	WriteTimeout time.Duration
+}
+
+type HubBrokerOption func(*HubBroker)
+
+func WithHubReadErrorFunc(readErrorFunc ReadErrorFunc) HubBrokerOption {
+	return func(b *HubBroker) {
+		b.ReadErrorFunc = readErrorFunc
+	}
+}
+
+func WithHubBackoff(backoff backoffutil.Backoff) HubBrokerOption {
+	return func(b *HubBroker) {
+		b.Backoff = backoff
+	}
+}
+
+func WithHubWriteTimeout(writeTimeout time.Duration) HubBrokerOption {
+	return func(b *HubBroker) {
+		b.WriteTimeout = writeTimeout
+	}
+}
+
+// DialHubBroker connects to the BrokerHub at url (ws:// or wss://). header is sent on
+// every (re)connection, e.g. to authenticate the node.
+func DialHubBroker(ctx context.Context, url string, header http.Header, options ...HubBrokerOption) (*HubBroker, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	b := &HubBroker{
+		url:           url,
+		header:        header,
+		conn:          &SafeConn{WebSocketConn: conn},
+		handlers:      make(map[int]BrokerHandler),
+		done:          make(chan struct{}),
+		ReadErrorFunc: DefaultReadErrorFunc,
+		Backoff:       backoffutil.DefaultBackoff,
+	}
+	for _, option := range options {
+		option(b)
+	}
+	if b.WriteTimeout <= 0 {
+		b.WriteTimeout = DefaultHubWriteTimeout
+	}
+	go b.readLoop()
+	return b, nil
+}
+
+// Connected reports whether the broker is currently connected to the hub.
+func (b *HubBroker) Connected() bool {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+	return b.conn != nil
+}
+
+func (b *HubBroker) readLoop() {
+	for {
+		b.connMu.RLock()
+		conn := b.conn
+		b.connMu.RUnlock()
+		err := b.read(conn)
+
+		b.connMu.Lock()
+		b.conn = nil
+		b.connMu.Unlock()
+		conn.Close()
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+		b.ReadErrorFunc(err)
+		if !b.reconnect() {
+			return
+		}
+	}
+}
+
+// read dispatches the messages of conn until it fails.
+func (b *HubBroker) read(conn *SafeConn) error {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		msg := &BrokerMessage{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			b.ReadErrorFunc(err)
+			continue
+		}
+		b.mu.RLock()
+		for _, handler := range b.handlers {
+			handler(msg)
+		}
+		b.mu.RUnlock()
+	}
+}
+
+// reconnect dials the hub until it succeeds or the broker is closed.
+func (b *HubBroker) reconnect() bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-b.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for attempt := 0; ; attempt++ {
+		timer := time.NewTimer(b.Backoff.Delay(attempt))
+		select {
+		case <-timer.C:
+		case <-b.done:
+			timer.Stop()
+			return false
+		}
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, b.url, b.header)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			b.ReadErrorFunc(err)
+			continue
+		}
+		b.connMu.Lock()
+		select {
+		case <-b.done:
+			b.connMu.Unlock()
+			conn.Close()
+			return false
+		default:
+		}
+		b.conn = &SafeConn{WebSocketConn: conn}
+		b.connMu.Unlock()
+		return true
+	}
+}
+
+func (b *HubBroker) Publish(ctx context.Context, msg *BrokerMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	b.connMu.RLock()
+	conn := b.conn
+	b.connMu.RUnlock()
+	if conn == nil {
+		return ErrBrokerDisconnected
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(b.WriteTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.writeMu.Lock()
+	conn.SetWriteDeadline(deadline)
+	err = conn.WriteMessage(websocket.TextMessage, data)
+	conn.writeMu.Unlock()
+	if err != nil {
+		// 写超时后连接不可再用，关闭后由 readLoop 重连
+		conn.Close()
+	}
+	return err
+}
+
+func (b *HubBroker) Subscribe(ctx context.Context, handler BrokerHandler) error {
+	b.mu.Lock()
+	id := b.nextId
+	b.nextId++
+	b.handlers[id] = handler
+	b.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}()
+	return nil
+}
+
+// Close disconnects from the hub and stops reconnecting.
+func (b *HubBroker) Close() error {
+	b.once.Do(func() {
+		b.connMu.Lock()
+		close(b.done)
+		b.connMu.Unlock()
+	})
+	b.connMu.RLock()
+	conn := b.conn
+	b.connMu.RUnlock()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
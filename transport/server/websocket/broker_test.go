@@ -0,0 +1,263 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vkviyu/nexus/utils/backoffutil"
+)
+
+func TestManagerBrokerFanOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := NewMemoryBroker()
+	idAuth := func(r *http.Request) (bool, string) {
+		return true, r.URL.Query().Get("id")
+	}
+
+	epA := NewEndpoint("/ws", WithAuthFunc(idAuth))
+	nodeA := NewManager(WithBroker(broker), WithNodeId("a"))
+	nodeA.AddEndpoint(epA)
+	epB := NewEndpoint("/ws", WithAuthFunc(idAuth))
+	nodeB := NewManager(WithBroker(broker), WithNodeId("b"))
+	nodeB.AddEndpoint(epB)
+	for _, node := range []*Manager{nodeA, nodeB} {
+		if err := node.Start(ctx); err != nil {
+			t.Fatalf("start failed: %v", err)
+		}
+	}
+
+	conn := dialTestEndpoint(t, epB, "?id=alice")
+	waitForConn(t, epB, "alice")
+
+	err := nodeA.SendMessage(&EndpointMessage{
+		Message:      Message{Message: []byte("hello"), ConnIds: []ConnId{"alice"}},
+		EndpointPath: "/ws",
+	})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("expected hello, got %s", data)
+	}
+}
+
+func TestManagerBrokerReliable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := NewMemoryBroker()
+	store := NewMemoryOfflineStore() // 节点共享的存储
+	idAuth := func(r *http.Request) (bool, string) {
+		return true, r.URL.Query().Get("id")
+	}
+	epA := NewEndpoint("/ws", WithAuthFunc(idAuth), WithOfflineStore(store))
+	nodeA := NewManager(WithBroker(broker), WithNodeId("a"))
+	nodeA.AddEndpoint(epA)
+	epB := NewEndpoint("/ws", WithAuthFunc(idAuth), WithOfflineStore(store))
+	nodeB := NewManager(WithBroker(broker), WithNodeId("b"))
+	nodeB.AddEndpoint(epB)
+	for _, node := range []*Manager{nodeA, nodeB} {
+		if err := node.Start(ctx); err != nil {
+			t.Fatalf("start failed: %v", err)
+		}
+	}
+
+	conn := dialTestEndpoint(t, epB, "?id=alice")
+	waitForConn(t, epB, "alice")
+
+	err := nodeA.SendMessage(&EndpointMessage{
+		Message:      Message{Message: []byte("hello"), ConnIds: []ConnId{"alice"}, Reliable: true},
+		EndpointPath: "/ws",
+	})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var frame DeliveryFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if frame.Text != "hello" {
+		t.Fatalf("unexpected delivery frame: %+v", frame)
+	}
+	if pending, _ := store.Load("/ws", "alice"); len(pending) != 1 || pending[0].ID != frame.ID {
+		t.Fatalf("expected one pending message with the delivered ID, got %+v", pending)
+	}
+
+	if err := conn.WriteJSON(AckFrame{Ack: frame.ID}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		pending, _ := store.Load("/ws", "alice")
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("acknowledged message is still pending")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManagerSendMessageWithoutBroker(t *testing.T) {
+	manager := NewManager()
+	manager.AddEndpoint(NewEndpoint("/ws"))
+
+	err := manager.SendMessage(&EndpointMessage{
+		Message:      Message{Message: []byte("hello"), ConnIds: []ConnId{"nobody"}},
+		EndpointPath: "/ws",
+	})
+	if err == nil {
+		t.Fatal("expected ConnNotFoundError")
+	}
+}
+
+func waitForConn(t *testing.T, ep *Endpoint, connId ConnId) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for ep.GetConn(connId) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("connection %s not registered", connId)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHubBrokerAuthAndReconnect(t *testing.T) {
+	hub := NewBrokerHub(WithHubAuthFunc(func(r *http.Request) (bool, string) {
+		return r.Header.Get("X-Node-Token") == "secret", "node"
+	}))
+	var (
+		connsMu sync.Mutex
+		conns   []*WebSocketConn
+	)
+	hub.UpgradeFunc = func(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+		conn, err := DefaultUpgradeFunc(w, r)
+		if err == nil {
+			connsMu.Lock()
+			conns = append(conns, conn)
+			connsMu.Unlock()
+		}
+		return conn, err
+	}
+	server := httptest.NewServer(hub)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	ctx := context.Background()
+
+	if _, err := DialHubBroker(ctx, url, nil); err == nil {
+		t.Fatal("expected unauthenticated node to be rejected")
+	}
+
+	header := http.Header{"X-Node-Token": {"secret"}}
+	readErrors := make(chan error, 16)
+	fastBackoff := WithHubBackoff(backoffutil.Backoff{Initial: 10 * time.Millisecond, Max: 10 * time.Millisecond})
+	receiver, err := DialHubBroker(ctx, url, header, fastBackoff, WithHubReadErrorFunc(func(err error) {
+		select {
+		case readErrors <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer receiver.Close()
+	sender, err := DialHubBroker(ctx, url, header, fastBackoff)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer sender.Close()
+
+	received := make(chan *BrokerMessage, 16)
+	receiver.Subscribe(ctx, func(msg *BrokerMessage) { received <- msg })
+
+	// 断开所有节点，等待两端重连
+	connsMu.Lock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	connsMu.Unlock()
+	select {
+	case <-readErrors:
+	case <-time.After(time.Second):
+		t.Fatal("expected the connection failure to be reported")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		// 重连后 hub 需注册两端，重试发布直到消息送达
+		sender.Publish(ctx, &BrokerMessage{NodeId: "a"})
+		select {
+		case msg := <-received:
+			if msg.NodeId != "a" {
+				t.Fatalf("unexpected message %+v", msg)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected delivery after reconnecting")
+		}
+	}
+}
+
+func TestHubBrokerPublishTimeout(t *testing.T) {
+	// 不读取消息的 hub：发送缓冲区写满后 Publish 必须超时返回，而不是永久阻塞
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := DefaultUpgradeFunc(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-stalled
+	}))
+	defer server.Close()
+	defer close(stalled)
+
+	broker, err := DialHubBroker(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil,
+		WithHubWriteTimeout(50*time.Millisecond), WithHubReadErrorFunc(func(err error) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+
+	msg := &BrokerMessage{EndpointMessage: EndpointMessage{Message: Message{Message: make([]byte, 1<<20)}}}
+	done := make(chan error, 1)
+	go func() {
+		for {
+			if err := broker.Publish(context.Background(), msg); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-done:
+		var netErr interface{ Timeout() bool }
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("expected a timeout, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a stalled hub")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := broker.Publish(ctx, msg); err == nil {
+		t.Error("expected Publish with a done context to fail")
+	}
+}
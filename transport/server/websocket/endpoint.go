@@ -1,23 +1,23 @@
 package websocket
 
 import (
+	"context"
 	"maps"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/vkviyu/nexus/transport/auth"
 )
 
-// Upgrader specifies parameters for upgrading an HTTP connection to a WebSocket connection.
+// DefaultUpgrader specifies parameters for upgrading an HTTP connection to a WebSocket connection.
 // It is safe to call Upgrader's methods concurrently.
+// CheckOrigin is nil, so cross-origin browser requests are rejected; use WithAllowedOrigins
+// on an Endpoint to allow other origins.
 var DefaultUpgrader = Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	// Allow all origins
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
 }
 
 // Upgrader is a alias for gorilla/websocket.Upgrader.
@@ -74,19 +74,46 @@ type EndpointPath = string
 
 type MsgChan = chan *EndpointMessage
 
+// ConnMeta describes an established connection.
+type ConnMeta struct {
+	ConnId ConnId
+	// Subprotocol is the negotiated subprotocol, empty if none was negotiated.
+	Subprotocol string
+	Origin      string
+	RemoteAddr  string
+	ConnectedAt time.Time
+}
+
 // SafeConn wraps WebSocketConn with a mutex to ensure write safety.
 // gorilla/websocket connections support one concurrent reader and one concurrent writer,
 // so we need to serialize write operations.
 type SafeConn struct {
 	*WebSocketConn
-	writeMu sync.Mutex
+	Meta ConnMeta
+	// Principal is the identity that authenticated the connection, with only an ID when the
+	// endpoint uses an AuthFunc.
+	Principal *auth.Principal
+	writeMu   sync.Mutex
+	limiter   *tokenBucket
+
+	counters         trafficCounters
+	endpointCounters *trafficCounters
 }
 
 // SafeWriteMessage writes a message to the connection with mutex protection.
 func (sc *SafeConn) SafeWriteMessage(messageType int, data []byte) error {
+	sc.writeMu.Lock()
+	err := sc.WriteMessage(messageType, data)
+	sc.writeMu.Unlock()
+	sc.recordOut(len(data), err)
+	return err
+}
+
+// SafeWriteControl writes a control message (close, ping, pong) with mutex protection.
+func (sc *SafeConn) SafeWriteControl(messageType int, data []byte, deadline time.Time) error {
 	sc.writeMu.Lock()
 	defer sc.writeMu.Unlock()
-	return sc.WriteMessage(messageType, data)
+	return sc.WriteControl(messageType, data, deadline)
 }
 
 type ConnMap = map[ConnId]*SafeConn
@@ -95,16 +122,46 @@ type UpgraderFunc func(w http.ResponseWriter, r *http.Request) (*WebSocketConn,
 type UpgradeFailFunc func(rw http.ResponseWriter, r *http.Request)
 type ReadErrorFunc func(err error)
 
+// DefaultCloseCode and DefaultCloseReason are sent in the close frame when an endpoint shuts down.
+var (
+	DefaultCloseCode   = CloseGoingAway
+	DefaultCloseReason = "server shutting down"
+)
+
+// DefaultCloseWriteTimeout bounds the time spent writing the close frame to a single connection.
+var DefaultCloseWriteTimeout = time.Second
+
 type Endpoint struct {
 	EndpointPath    EndpointPath
 	AuthFunc        auth.AuthFunc
+	Authenticator   auth.Authenticator
 	AuthFailFunc    auth.AuthFailFunc
 	MsgChan         MsgChan
 	UpgradeFunc     UpgraderFunc
 	UpgradeFailFunc UpgradeFailFunc
 	ReadErrorFunc   ReadErrorFunc
 	ConnMap         map[ConnId]*SafeConn
-	connMu          sync.RWMutex
+	CloseCode       int
+	CloseReason     string
+	OfflineStore    OfflineStore
+	// AllowedOrigins, Subprotocols and EnableCompression configure the upgrader used while
+	// UpgradeFunc is nil. It is built for every upgrade, so SetOptions applies to later
+	// connections. They are ignored when a custom UpgradeFunc is set.
+	AllowedOrigins    []string
+	Subprotocols      []string
+	EnableCompression bool
+	// ReadLimit is the maximum message size in bytes, DefaultReadLimit if zero, unlimited if negative.
+	ReadLimit int64
+	RateLimit *RateLimit
+
+	rateLimitCounters rateLimitCounters
+	traffic           trafficCounters
+	connections       connectionCounters
+	connMu            sync.RWMutex
+	closed            bool
+	done              chan struct{}
+	closeOnce         sync.Once
+	readers           sync.WaitGroup
 }
 
 func NewEndpoint(path EndpointPath, options ...EndpointOption) *Endpoint {
@@ -130,6 +187,14 @@ func WithAuthFunc(authFunc auth.AuthFunc) EndpointOption {
 	}
 }
 
+// WithAuthenticator authenticates connections with an auth.Authenticator, which takes precedence
+// over the AuthFunc. The principal ID is the ConnId and the principal is SafeConn.Principal.
+func WithAuthenticator(authenticator auth.Authenticator) EndpointOption {
+	return func(e *Endpoint) {
+		e.Authenticator = authenticator
+	}
+}
+
 func WithAuthFailFunc(authFailFunc auth.AuthFailFunc) EndpointOption {
 	return func(e *Endpoint) {
 		e.AuthFailFunc = authFailFunc
@@ -166,6 +231,14 @@ func WithConnMap(connMap ConnMap) EndpointOption {
 	}
 }
 
+// WithCloseFrame sets the close code and reason sent to clients when the endpoint shuts down.
+func WithCloseFrame(code int, reason string) EndpointOption {
+	return func(e *Endpoint) {
+		e.CloseCode = code
+		e.CloseReason = reason
+	}
+}
+
 func (e *Endpoint) applyDefaultsIfNil() {
 	if e.AuthFunc == nil {
 		e.AuthFunc = auth.DefaultAuthFunc
@@ -173,9 +246,6 @@ func (e *Endpoint) applyDefaultsIfNil() {
 	if e.AuthFailFunc == nil {
 		e.AuthFailFunc = auth.DefaultAuthFailFunc
 	}
-	if e.UpgradeFunc == nil {
-		e.UpgradeFunc = DefaultUpgradeFunc
-	}
 	if e.UpgradeFailFunc == nil {
 		e.UpgradeFailFunc = DefaultUpgradeFailFunc
 	}
@@ -188,36 +258,119 @@ func (e *Endpoint) applyDefaultsIfNil() {
 	if e.ConnMap == nil {
 		e.ConnMap = make(map[ConnId]*SafeConn)
 	}
+	if e.ReadLimit == 0 {
+		e.ReadLimit = DefaultReadLimit
+	}
+	if e.CloseCode == 0 {
+		e.CloseCode = DefaultCloseCode
+		if e.CloseReason == "" {
+			e.CloseReason = DefaultCloseReason
+		}
+	}
+	if e.done == nil {
+		e.done = make(chan struct{})
+	}
 }
 
 func (e *Endpoint) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	authResult, connId := e.AuthFunc(r)
-	if !authResult {
+	e.connMu.Lock()
+	if e.closed {
+		e.connMu.Unlock()
+		http.Error(rw, "WebSocket endpoint is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	e.readers.Add(1)
+	e.connMu.Unlock()
+	defer e.readers.Done()
+
+	authenticator := e.Authenticator
+	if authenticator == nil {
+		authenticator = auth.FromAuthFunc(e.AuthFunc)
+	}
+	principal, err := authenticator.Authenticate(r)
+	if err != nil || principal == nil {
 		e.AuthFailFunc(rw, r)
 		return
 	}
-	conn, err := e.UpgradeFunc(rw, r)
+	connId := principal.ID
+	upgradeFunc := e.UpgradeFunc
+	if upgradeFunc == nil {
+		upgradeFunc = e.newUpgradeFunc()
+	}
+	conn, err := upgradeFunc(rw, r)
 	if err != nil {
 		e.UpgradeFailFunc(rw, r)
 		return
 	}
-	safeConn := &SafeConn{WebSocketConn: conn}
+	if e.ReadLimit > 0 {
+		conn.SetReadLimit(e.ReadLimit)
+	}
+	safeConn := &SafeConn{
+		WebSocketConn: conn,
+		Meta: ConnMeta{
+			ConnId:      connId,
+			Subprotocol: conn.Subprotocol(),
+			Origin:      r.Header.Get("Origin"),
+			RemoteAddr:  r.RemoteAddr,
+			ConnectedAt: time.Now(),
+		},
+		Principal: principal,
+	}
+	safeConn.endpointCounters = &e.traffic
+	if e.RateLimit != nil && e.RateLimit.Rate > 0 {
+		safeConn.limiter = newTokenBucket(e.RateLimit.Rate, e.RateLimit.Burst)
+	}
 	e.connMu.Lock()
+	if e.closed {
+		// Close started during the upgrade and won't see this connection.
+		e.connMu.Unlock()
+		e.writeCloseFrame(safeConn)
+		conn.Close()
+		return
+	}
+	replaced := e.ConnMap[connId]
 	e.ConnMap[connId] = safeConn
 	e.connMu.Unlock()
+	if replaced != nil {
+		// 同一 ConnId 重新连接，关闭旧连接；旧连接退出时不会删除新连接
+		writeCloseFrame(replaced, ClosePolicyViolation, "replaced by a new connection")
+		replaced.Close()
+	}
+	e.connections.total.Add(1)
+	if e.OfflineStore != nil {
+		e.flushPending(connId, safeConn)
+	}
 	defer func() {
 		e.connMu.Lock()
-		delete(e.ConnMap, connId)
+		if e.ConnMap[connId] == safeConn {
+			delete(e.ConnMap, connId)
+		}
 		e.connMu.Unlock()
 		conn.Close()
+		e.connections.closed.Add(1)
+		e.connections.closedDurationNanos.Add(int64(time.Since(safeConn.Meta.ConnectedAt)))
 	}()
 	for {
 		messageType, message, err := conn.ReadMessage()
 		if err != nil {
+			e.recordReadError(err)
 			e.ReadErrorFunc(err)
 			return
 		}
-		e.MsgChan <- &EndpointMessage{
+		safeConn.recordIn(len(message))
+		if e.RateLimit != nil {
+			allow, closed := e.checkRateLimit(safeConn, message)
+			if closed {
+				return
+			}
+			if !allow {
+				continue
+			}
+		}
+		if e.OfflineStore != nil && e.handleAck(connId, messageType, message) {
+			continue
+		}
+		endpointMsg := &EndpointMessage{
 			Message: Message{
 				MessageType: MessageType(messageType),
 				Message:     message,
@@ -225,9 +378,79 @@ func (e *Endpoint) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 			},
 			EndpointPath: e.EndpointPath,
 		}
+		select {
+		case e.MsgChan <- endpointMsg:
+		case <-e.done:
+			return
+		}
 	}
 }
 
+// Close shuts the endpoint down gracefully: new upgrades are rejected with 503, every
+// connection receives a close frame with CloseCode and CloseReason, and Close waits for
+// all read loops to exit before closing MsgChan. If ctx is done first, the remaining
+// connections are closed forcibly and ctx.Err() is returned.
+//
+// MsgChan is closed, so a channel shared between endpoints via WithMsgChan must be
+// shut down through Manager.Shutdown instead.
+//
+// Example with the stopctx of cmd.Program:
+//
+//	<-stopctx.Done()
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	endpoint.Close(ctx)
+func (e *Endpoint) Close(ctx context.Context) error {
+	err := e.shutdown(ctx)
+	e.closeOnce.Do(func() {
+		close(e.MsgChan)
+	})
+	return err
+}
+
+// shutdown does everything Close does except closing MsgChan.
+func (e *Endpoint) shutdown(ctx context.Context) error {
+	e.connMu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.done)
+	}
+	connMapCopy := make(map[ConnId]*SafeConn, len(e.ConnMap))
+	maps.Copy(connMapCopy, e.ConnMap)
+	e.connMu.Unlock()
+
+	for _, conn := range connMapCopy {
+		e.writeCloseFrame(conn)
+	}
+
+	readersDone := make(chan struct{})
+	go func() {
+		e.readers.Wait()
+		close(readersDone)
+	}()
+	select {
+	case <-readersDone:
+		return nil
+	case <-ctx.Done():
+	}
+	e.connMu.RLock()
+	for _, conn := range e.ConnMap {
+		conn.Close()
+	}
+	e.connMu.RUnlock()
+	<-readersDone
+	return ctx.Err()
+}
+
+func (e *Endpoint) writeCloseFrame(conn *SafeConn) error {
+	return writeCloseFrame(conn, e.CloseCode, e.CloseReason)
+}
+
+func writeCloseFrame(conn *SafeConn, code int, reason string) error {
+	data := websocket.FormatCloseMessage(code, reason)
+	return conn.SafeWriteControl(websocket.CloseMessage, data, time.Now().Add(DefaultCloseWriteTimeout))
+}
+
 func (e *Endpoint) GetConn(connId ConnId) *SafeConn {
 	e.connMu.RLock()
 	defer e.connMu.RUnlock()
@@ -237,12 +460,35 @@ func (e *Endpoint) GetConn(connId ConnId) *SafeConn {
 	return nil
 }
 
+// HasConn reports whether the connection is established on this endpoint.
+func (e *Endpoint) HasConn(connId ConnId) bool {
+	return e.GetConn(connId) != nil
+}
+
+func (e *Endpoint) GetEndpointPath() EndpointPath {
+	return e.EndpointPath
+}
+
 func (e *Endpoint) GetConnCount() int {
 	e.connMu.RLock()
 	defer e.connMu.RUnlock()
 	return len(e.ConnMap)
 }
 
+// Kick closes a connection with the given close code and reason.
+func (e *Endpoint) Kick(connId ConnId, code int, reason string) error {
+	conn := e.GetConn(connId)
+	if conn == nil {
+		return &ConnNotFoundError{EndpointPath: e.EndpointPath, ConnId: connId}
+	}
+	err := writeCloseFrame(conn, code, reason)
+	// Give the client a chance to complete the closing handshake.
+	time.AfterFunc(DefaultCloseWriteTimeout, func() {
+		conn.Close()
+	})
+	return err
+}
+
 func (e *Endpoint) GetMsgChan() MsgChan {
 	return e.MsgChan
 }
@@ -263,7 +509,17 @@ func (e *Endpoint) SendMessage(msg *Message) error {
 		}
 	} else {
 		// 发送到指定的连接
+		var stored *StoredMessage
+		if msg.Reliable && e.OfflineStore != nil {
+			stored = newStoredMessage(msg)
+		}
 		for _, connId := range msg.ConnIds {
+			if stored != nil {
+				if err := e.storeAndDeliver(connId, stored); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
 			conn := e.GetConn(connId)
 			if conn == nil {
 				errs = append(errs, &ConnNotFoundError{
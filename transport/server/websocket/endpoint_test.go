@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vkviyu/nexus/transport/auth"
+)
+
+func TestEndpointClose(t *testing.T) {
+	ep := NewEndpoint("/ws", WithCloseFrame(CloseServiceRestart, "restarting"))
+	conn := dialTestEndpoint(t, ep, "")
+	for ep.GetConnCount() == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Answer the close frame like a browser would.
+	clientErr := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		clientErr <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ep.Close(ctx); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	var closeErr *websocket.CloseError
+	if err := <-clientErr; !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseServiceRestart || closeErr.Text != "restarting" {
+		t.Errorf("expected close frame 1012 restarting, got %v", err)
+	}
+	if _, ok := <-ep.GetMsgChan(); ok {
+		t.Error("expected MsgChan to be closed")
+	}
+	if ep.GetConnCount() != 0 {
+		t.Errorf("expected no connections, got %d", ep.GetConnCount())
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	patterns := []string{"https://app.example.com", "*.example.org", "https://*.example.net", "localhost:3000", "https://secure.example.com:443", "[::1]:8080"}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"http://app.example.com", false},
+		{"https://other.example.com", false},
+		{"https://a.example.org", true},
+		{"http://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://x.example.net", true},
+		{"http://x.example.net", false},
+		{"http://localhost:3000", true},
+		{"http://localhost:4000", false},
+		{"https://app.example.com:8443", true},
+		{"https://a.example.org:8443", true},
+		{"https://secure.example.com", true},
+		{"https://secure.example.com:8443", false},
+		{"http://[::1]:8080", true},
+		{"http://[::1]:9090", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := OriginAllowed(tt.origin, patterns); got != tt.want {
+			t.Errorf("OriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestEndpointUpgradeOptions(t *testing.T) {
+	ep := NewEndpoint("/ws",
+		WithAllowedOrigins("https://*.example.com"),
+		WithSubprotocols("v2.json", "v1.json"),
+	)
+	server := httptest.NewServer(ep)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	header := http.Header{"Origin": {"https://evil.test"}}
+	if _, _, err := websocket.DefaultDialer.Dial(url, header); err == nil {
+		t.Fatal("expected disallowed origin to be rejected")
+	}
+
+	dialer := websocket.Dialer{Subprotocols: []string{"v1.json"}}
+	header = http.Header{"Origin": {"https://app.example.com"}}
+	conn, _, err := dialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != "v1.json" {
+		t.Errorf("expected negotiated subprotocol v1.json, got %q", conn.Subprotocol())
+	}
+
+	// SetOptions 对之后的升级生效
+	ep.SetOptions(WithAllowedOrigins("https://evil.test"))
+	other, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.test"}})
+	if err != nil {
+		t.Fatalf("expected origin allowed by SetOptions, got %v", err)
+	}
+	other.Close()
+	for ep.GetConnCount() > 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	for ep.GetConnCount() == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	ep.connMu.RLock()
+	defer ep.connMu.RUnlock()
+	for _, safeConn := range ep.ConnMap {
+		if safeConn.Meta.Subprotocol != "v1.json" || safeConn.Meta.Origin != "https://app.example.com" {
+			t.Errorf("unexpected connection metadata: %+v", safeConn.Meta)
+		}
+	}
+}
+
+func TestEndpointAuthenticator(t *testing.T) {
+	ep := NewEndpoint("/ws", WithAuthenticator(auth.AuthenticatorFunc(func(r *http.Request) (*auth.Principal, error) {
+		if r.URL.Query().Get("token") != "secret" {
+			return nil, auth.ErrUnauthenticated
+		}
+		return &auth.Principal{ID: "alice", Roles: []string{"admin"}}, nil
+	})))
+	dialTestEndpoint(t, ep, "?token=secret")
+	waitForConn(t, ep, "alice")
+	if conn := ep.GetConn("alice"); !conn.Principal.HasRole("admin") {
+		t.Errorf("expected principal with admin role, got %+v", conn.Principal)
+	}
+
+	server := httptest.NewServer(ep)
+	defer server.Close()
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %v", err)
+	}
+}
+
+func TestEndpointReplaceConn(t *testing.T) {
+	ep := NewEndpoint("/ws", WithAuthFunc(func(r *http.Request) (bool, string) {
+		return true, "alice"
+	}))
+	first := dialTestEndpoint(t, ep, "")
+	waitForConn(t, ep, "alice")
+	replaced := ep.GetConn("alice")
+	second := dialTestEndpoint(t, ep, "")
+	for ep.GetConn("alice") == replaced {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var closeErr *websocket.CloseError
+	if _, _, err := first.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != ClosePolicyViolation {
+		t.Errorf("expected the replaced connection to be closed, got %v", err)
+	}
+	// 旧连接的读循环退出后，新连接仍然在线
+	time.Sleep(50 * time.Millisecond)
+	if err := ep.SendMessage(&Message{MessageType: TextMessage, Message: []byte("hi"), ConnIds: []ConnId{"alice"}}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	if _, data, err := second.ReadMessage(); err != nil || string(data) != "hi" {
+		t.Errorf("expected hi on the new connection, got %q %v", data, err)
+	}
+}
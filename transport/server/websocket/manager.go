@@ -1,10 +1,51 @@
 package websocket
 
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
 // EndpointMap is a map of WebSocket endpoints.
 type EndpointMap map[EndpointPath]*Endpoint
 
+// MessageEndpoint is an endpoint of another transport, such as sse.Endpoint, that the Manager
+// routes messages to alongside its WebSocket endpoints. *Endpoint implements it as well.
+type MessageEndpoint interface {
+	GetEndpointPath() EndpointPath
+	// SendMessage delivers msg with the semantics of Endpoint.SendMessage.
+	SendMessage(msg *Message) error
+	HasConn(connId ConnId) bool
+	GetConnCount() int
+	Stats() EndpointStats
+	// Close shuts the endpoint down, see Endpoint.Close.
+	Close(ctx context.Context) error
+}
+
 type Manager struct {
 	EndpointMap EndpointMap
+	// MessageEndpoints holds the endpoints of other transports, see AddMessageEndpoint.
+	MessageEndpoints map[EndpointPath]MessageEndpoint
+	// NodeId identifies this process in a cluster. Defaults to a random UUID.
+	NodeId string
+	// Broker, if set, delivers messages to connections held by other nodes.
+	Broker Broker
+}
+
+type ManagerOption func(*Manager)
+
+func WithBroker(broker Broker) ManagerOption {
+	return func(m *Manager) {
+		m.Broker = broker
+	}
+}
+
+func WithNodeId(nodeId string) ManagerOption {
+	return func(m *Manager) {
+		m.NodeId = nodeId
+	}
 }
 
 func newEndpointMap() EndpointMap {
@@ -15,16 +56,30 @@ func (m EndpointMap) Add(endpointPath EndpointPath, endpoint *Endpoint) {
 	m[endpointPath] = endpoint
 }
 
-func NewManager() *Manager {
-	return &Manager{
-		EndpointMap: newEndpointMap(),
+func NewManager(options ...ManagerOption) *Manager {
+	manager := &Manager{
+		EndpointMap:      newEndpointMap(),
+		MessageEndpoints: make(map[EndpointPath]MessageEndpoint),
+	}
+	for _, option := range options {
+		option(manager)
 	}
+	if manager.NodeId == "" {
+		manager.NodeId = uuid.New().String()
+	}
+	return manager
 }
 
 func (s *Manager) AddEndpoint(endpoint *Endpoint) {
 	s.EndpointMap.Add(endpoint.EndpointPath, endpoint)
 }
 
+// AddMessageEndpoint registers an endpoint of another transport. Manager.SendMessage,
+// Shutdown and Stats include it, so broadcasts reach every transport through one API.
+func (s *Manager) AddMessageEndpoint(endpoint MessageEndpoint) {
+	s.MessageEndpoints[endpoint.GetEndpointPath()] = endpoint
+}
+
 func (s *Manager) GetEndpoint(endpointPath EndpointPath) *Endpoint {
 	return s.EndpointMap[endpointPath]
 }
@@ -39,6 +94,9 @@ func (s *Manager) GetConn(endpointPath EndpointPath, connId ConnId) *SafeConn {
 
 func (s *Manager) GetConnCount(endpointPath EndpointPath) int {
 	endpoint := s.GetEndpoint(endpointPath)
+	if endpoint == nil {
+		return 0
+	}
 	return endpoint.GetConnCount()
 }
 
@@ -48,4 +106,210 @@ func (s *Manager) GetMsgChan(endpointPath EndpointPath) MsgChan {
 		return nil
 	}
 	return endpoint.GetMsgChan()
-}
\ No newline at end of file
+}
+
+// Start subscribes the manager to its Broker so that messages published by other nodes
+// reach local connections. It is a no-op without a Broker. The subscription ends when ctx is done.
+func (s *Manager) Start(ctx context.Context) error {
+	if s.Broker == nil {
+		return nil
+	}
+	return s.Broker.Subscribe(ctx, func(msg *BrokerMessage) {
+		if msg.NodeId == s.NodeId {
+			return
+		}
+		// The targets may live on yet another node, so missing connections are not errors here.
+		s.sendLocal(&msg.EndpointMessage, msg.DeliveryId)
+	})
+}
+
+// Shutdown closes all endpoints concurrently (see Endpoint.Close), then closes the Broker
+// and every distinct MsgChan once. It is meant to be called when the program's stopctx is done:
+//
+//	<-stopctx.Done()
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	manager.Shutdown(ctx)
+//	server.Shutdown(ctx)
+func (s *Manager) Shutdown(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	collect := func(err error) {
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+	}
+	for _, endpoint := range s.EndpointMap {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			collect(endpoint.shutdown(ctx))
+		}()
+	}
+	for _, endpoint := range s.MessageEndpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			collect(endpoint.Close(ctx))
+		}()
+	}
+	wg.Wait()
+
+	if s.Broker != nil {
+		if err := s.Broker.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Endpoints may share a MsgChan, close each channel only once.
+	closedChans := make(map[MsgChan]bool)
+	for _, endpoint := range s.EndpointMap {
+		endpoint.closeOnce.Do(func() {
+			if !closedChans[endpoint.MsgChan] {
+				closedChans[endpoint.MsgChan] = true
+				close(endpoint.MsgChan)
+			}
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// SendMessage routes msg to the endpoints of this manager, see EndpointMessage for the send modes.
+// With a Broker, broadcasts and messages for connections that are not held locally are also
+// published to the other nodes, and ConnNotFoundError is no longer reported for them.
+//
+// Reliable messages for connections that are not held locally are stored by the endpoint's
+// OfflineStore, and with a Broker also published so that the node holding the connection
+// delivers them.
+func (s *Manager) SendMessage(msg *EndpointMessage) error {
+	ensureValidMessage(&msg.Message)
+	missing, errs := s.sendLocal(msg, "")
+	var stored *StoredMessage
+	if endpoint := s.offlineEndpoint(msg); endpoint != nil && len(missing) > 0 {
+		stored = newStoredMessage(&msg.Message)
+		for _, connId := range missing {
+			errs = appendSendErrors(errs, endpoint.storeAndDeliver(connId, stored))
+		}
+	}
+	if s.Broker == nil {
+		if msg.EndpointPath != "" && s.getMessageEndpoint(msg.EndpointPath) == nil {
+			errs = append(errs, &EndpointNotFoundError{EndpointPath: msg.EndpointPath})
+		} else if stored == nil {
+			for _, connId := range missing {
+				errs = append(errs, &ConnNotFoundError{EndpointPath: msg.EndpointPath, ConnId: connId})
+			}
+		}
+	} else if len(msg.ConnIds) == 0 || len(missing) > 0 {
+		remote := &BrokerMessage{NodeId: s.NodeId, EndpointMessage: *msg}
+		remote.ConnIds = missing
+		if stored != nil {
+			remote.DeliveryId = stored.ID
+		}
+		if err := s.Broker.Publish(context.Background(), remote); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return &MessageSendError{
+			EndpointPath: msg.EndpointPath,
+			Errors:       errs,
+		}
+	}
+	return nil
+}
+
+// sendLocal delivers msg to local connections. It returns the targeted ConnIds
+// that are not connected to this node, and the other delivery errors.
+// deliveryId is the ID of a Reliable message stored by another node, empty otherwise.
+func (s *Manager) sendLocal(msg *EndpointMessage, deliveryId string) (missing []ConnId, errs []error) {
+	var endpoints []MessageEndpoint
+	if msg.EndpointPath == "" {
+		for _, endpoint := range s.EndpointMap {
+			endpoints = append(endpoints, endpoint)
+		}
+		for _, endpoint := range s.MessageEndpoints {
+			endpoints = append(endpoints, endpoint)
+		}
+	} else if endpoint := s.getMessageEndpoint(msg.EndpointPath); endpoint != nil {
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if len(msg.ConnIds) == 0 {
+		for _, endpoint := range endpoints {
+			errs = appendSendErrors(errs, endpoint.SendMessage(&msg.Message))
+		}
+		return nil, errs
+	}
+
+	found := make(map[ConnId]bool, len(msg.ConnIds))
+	for _, endpoint := range endpoints {
+		local := msg.Message
+		local.ConnIds = nil
+		for _, connId := range msg.ConnIds {
+			if endpoint.HasConn(connId) {
+				local.ConnIds = append(local.ConnIds, connId)
+				found[connId] = true
+			}
+		}
+		if len(local.ConnIds) == 0 {
+			continue
+		}
+		if ep := s.offlineEndpoint(msg); ep != nil && deliveryId != "" {
+			// 沿用发送节点存储的 ID，确认后两处副本使用同一 ID
+			stored := newStoredMessage(&local)
+			stored.ID = deliveryId
+			for _, connId := range local.ConnIds {
+				errs = appendSendErrors(errs, ep.storeAndDeliver(connId, stored))
+			}
+			continue
+		}
+		errs = appendSendErrors(errs, endpoint.SendMessage(&local))
+	}
+	for _, connId := range msg.ConnIds {
+		if !found[connId] {
+			missing = append(missing, connId)
+		}
+	}
+	return missing, errs
+}
+
+// getMessageEndpoint looks an endpoint up among the endpoints of all transports.
+func (s *Manager) getMessageEndpoint(endpointPath EndpointPath) MessageEndpoint {
+	if endpoint := s.GetEndpoint(endpointPath); endpoint != nil {
+		return endpoint
+	}
+	if endpoint, ok := s.MessageEndpoints[endpointPath]; ok {
+		return endpoint
+	}
+	return nil
+}
+
+// offlineEndpoint returns the endpoint that stores msg for absent connections, nil if msg
+// is not Reliable or does not target connections of an endpoint with an OfflineStore.
+func (s *Manager) offlineEndpoint(msg *EndpointMessage) *Endpoint {
+	if !msg.Reliable || msg.EndpointPath == "" || len(msg.ConnIds) == 0 {
+		return nil
+	}
+	endpoint := s.GetEndpoint(msg.EndpointPath)
+	if endpoint == nil || endpoint.OfflineStore == nil {
+		return nil
+	}
+	return endpoint
+}
+
+// appendSendErrors flattens the errors of a MessageSendError into errs.
+func appendSendErrors(errs []error, err error) []error {
+	if err == nil {
+		return errs
+	}
+	var sendErr *MessageSendError
+	if errors.As(err, &sendErr) {
+		return append(errs, sendErr.Errors...)
+	}
+	return append(errs, err)
+}
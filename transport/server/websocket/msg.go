@@ -1,5 +1,7 @@
 package websocket
 
+import "github.com/gorilla/websocket"
+
 // MessageType represents the type of a message.
 // TextMessage represents a text message.
 // BinaryMessage represents a binary message.
@@ -14,6 +16,17 @@ const (
 
 var DefaultMessageType MessageType = TextMessage
 
+// Close codes defined in RFC 6455, section 11.7.
+const (
+	CloseNormalClosure     = websocket.CloseNormalClosure
+	CloseGoingAway         = websocket.CloseGoingAway
+	ClosePolicyViolation   = websocket.ClosePolicyViolation
+	CloseMessageTooBig     = websocket.CloseMessageTooBig
+	CloseInternalServerErr = websocket.CloseInternalServerErr
+	CloseServiceRestart    = websocket.CloseServiceRestart
+	CloseTryAgainLater     = websocket.CloseTryAgainLater
+)
+
 // Message is the basic message structure for WebSocket communication.
 // Used by Endpoint.SendMessage() for sending messages within an endpoint.
 // The send mode is determined by ConnIds:
@@ -24,6 +37,9 @@ type Message struct {
 	MessageType MessageType
 	Message     []byte
 	ConnIds     []ConnId // Target connections, empty means all connections
+	// Reliable sends the message with store-and-forward when the endpoint has an OfflineStore,
+	// see OfflineStore. Other messages, such as Router replies, are always written directly.
+	Reliable bool
 }
 
 // EndpointMessage is the message structure for Manager-level communication.
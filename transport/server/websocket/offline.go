@@ -0,0 +1,248 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StoredMessage is a message kept by an OfflineStore until its recipient acknowledges it.
+type StoredMessage struct {
+	ID          string      `json:"id"`
+	MessageType MessageType `json:"messageType"`
+	Message     []byte      `json:"message"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+// OfflineStore persists unacknowledged messages per connection.
+//
+// Setting an OfflineStore on an Endpoint (see WithOfflineStore) enables store-and-forward
+// with at-least-once delivery for messages marked Reliable:
+//   - every reliable message sent to explicit ConnIds is saved before it is written, and sent as a DeliveryFrame
+//   - reliable messages for ConnIds that are not connected are kept instead of failing with ConnNotFoundError
+//   - when a connection with the same ConnId is established, its pending messages are sent again
+//   - a message is removed once the client answers with an AckFrame carrying its ID
+//
+// Other messages and broadcasts (empty ConnIds) are best effort: they are neither stored nor framed.
+//
+// With a Broker, a reliable message for a connection held by another node is saved by the
+// sending node as well as by the node holding the connection, under the same ID. Share one
+// store between the nodes (e.g. a database) so that an acknowledgement clears both copies;
+// with local stores the client may receive the message again when it reconnects to the
+// sending node.
+type OfflineStore interface {
+	// Save adds a pending message for the connection. Saving an ID that is already pending
+	// replaces the message.
+	Save(endpointPath EndpointPath, connId ConnId, msg *StoredMessage) error
+	// Load returns the pending messages of the connection, oldest first.
+	Load(endpointPath EndpointPath, connId ConnId) ([]*StoredMessage, error)
+	// Delete removes an acknowledged message. Unknown IDs are ignored.
+	Delete(endpointPath EndpointPath, connId ConnId, msgId string) error
+}
+
+// DefaultOfflineMaxPending and DefaultOfflineTTL are the limits the offline stores are
+// created with, so that messages for connections that never come back do not pile up.
+// Beyond MaxPending messages per connection the oldest is dropped, and messages older than
+// TTL are no longer delivered. Zero disables a limit.
+var (
+	DefaultOfflineMaxPending = 1000
+	DefaultOfflineTTL        = 7 * 24 * time.Hour
+)
+
+// DeliveryFrame wraps a message sent to a client in store-and-forward mode.
+// JSON payloads are embedded in Data, other text payloads in Text,
+// and binary payloads are base64 encoded in Binary.
+type DeliveryFrame struct {
+	ID     string          `json:"id"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	Binary []byte          `json:"binary,omitempty"`
+}
+
+// AckFrame is sent by clients to acknowledge a DeliveryFrame: {"ack": "<id>"}.
+// Only text messages of exactly this shape are consumed by the endpoint, they never reach
+// MsgChan; any other message, even with an "ack" field, is passed on.
+type AckFrame struct {
+	Ack string `json:"ack"`
+}
+
+func WithOfflineStore(store OfflineStore) EndpointOption {
+	return func(e *Endpoint) {
+		e.OfflineStore = store
+	}
+}
+
+func newStoredMessage(msg *Message) *StoredMessage {
+	return &StoredMessage{
+		ID:          uuid.New().String(),
+		MessageType: msg.MessageType,
+		Message:     msg.Message,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func encodeDeliveryFrame(msg *StoredMessage) ([]byte, error) {
+	frame := DeliveryFrame{ID: msg.ID}
+	switch {
+	case msg.MessageType == BinaryMessage:
+		frame.Binary = msg.Message
+	case json.Valid(msg.Message):
+		frame.Data = msg.Message
+	default:
+		frame.Text = string(msg.Message)
+	}
+	return json.Marshal(frame)
+}
+
+// storeAndDeliver saves stored for connId and writes it if the connection is present.
+// Write failures are not reported: the message stays pending and is sent again on reconnect.
+func (e *Endpoint) storeAndDeliver(connId ConnId, stored *StoredMessage) error {
+	if err := e.OfflineStore.Save(e.EndpointPath, connId, stored); err != nil {
+		return err
+	}
+	if conn := e.GetConn(connId); conn != nil {
+		e.deliver(conn, stored)
+	}
+	return nil
+}
+
+func (e *Endpoint) deliver(conn *SafeConn, msg *StoredMessage) error {
+	data, err := encodeDeliveryFrame(msg)
+	if err != nil {
+		return err
+	}
+	return conn.SafeWriteMessage(int(TextMessage), data)
+}
+
+// flushPending sends the pending messages of a newly established connection.
+func (e *Endpoint) flushPending(connId ConnId, conn *SafeConn) {
+	pending, err := e.OfflineStore.Load(e.EndpointPath, connId)
+	if err != nil {
+		e.ReadErrorFunc(err)
+		return
+	}
+	for _, msg := range pending {
+		if err := e.deliver(conn, msg); err != nil {
+			e.ReadErrorFunc(err)
+			return
+		}
+	}
+}
+
+// handleAck consumes the message if it is an AckFrame.
+func (e *Endpoint) handleAck(connId ConnId, messageType int, message []byte) bool {
+	if MessageType(messageType) != TextMessage {
+		return false
+	}
+	var ack AckFrame
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ack); err != nil || ack.Ack == "" || decoder.More() {
+		return false
+	}
+	if err := e.OfflineStore.Delete(e.EndpointPath, connId, ack.Ack); err != nil {
+		e.ReadErrorFunc(err)
+	}
+	return true
+}
+
+// MemoryOfflineStore is an in-memory OfflineStore. Pending messages are lost on restart.
+// Save prunes expired messages of every connection.
+type MemoryOfflineStore struct {
+	// MaxPending and TTL limit the pending messages, see DefaultOfflineMaxPending.
+	MaxPending int
+	TTL        time.Duration
+
+	messages  map[string][]*StoredMessage
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+func NewMemoryOfflineStore() *MemoryOfflineStore {
+	return &MemoryOfflineStore{
+		MaxPending: DefaultOfflineMaxPending,
+		TTL:        DefaultOfflineTTL,
+		messages:   make(map[string][]*StoredMessage),
+	}
+}
+
+func offlineKey(endpointPath EndpointPath, connId ConnId) string {
+	return endpointPath + "\x00" + connId
+}
+
+func (s *MemoryOfflineStore) Save(endpointPath EndpointPath, connId ConnId, msg *StoredMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	// 顺便清理所有连接的过期消息，包括不再重连的连接
+	if s.TTL > 0 && now.Sub(s.lastPrune) > time.Minute {
+		for key := range s.messages {
+			s.prune(key, now)
+		}
+		s.lastPrune = now
+	}
+	key := offlineKey(endpointPath, connId)
+	if i := slices.IndexFunc(s.messages[key], func(pending *StoredMessage) bool {
+		return pending.ID == msg.ID
+	}); i >= 0 {
+		s.messages[key][i] = msg
+	} else {
+		s.messages[key] = append(s.messages[key], msg)
+	}
+	s.prune(key, now)
+	return nil
+}
+
+// prune drops the expired messages of key and the oldest ones beyond MaxPending.
+func (s *MemoryOfflineStore) prune(key string, now time.Time) {
+	kept, dropped := prunePending(s.messages[key], s.MaxPending, s.TTL, now)
+	switch {
+	case len(kept) == 0:
+		delete(s.messages, key)
+	case len(dropped) > 0:
+		s.messages[key] = slices.Clone(kept)
+	}
+}
+
+func (s *MemoryOfflineStore) Load(endpointPath EndpointPath, connId ConnId) ([]*StoredMessage, error) {
+	s.mu.Lock()
+	messages := slices.Clone(s.messages[offlineKey(endpointPath, connId)])
+	s.mu.Unlock()
+	messages, _ = prunePending(messages, 0, s.TTL, time.Now())
+	return messages, nil
+}
+
+func (s *MemoryOfflineStore) Delete(endpointPath EndpointPath, connId ConnId, msgId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := offlineKey(endpointPath, connId)
+	s.messages[key] = slices.DeleteFunc(s.messages[key], func(msg *StoredMessage) bool {
+		return msg.ID == msgId
+	})
+	if len(s.messages[key]) == 0 {
+		delete(s.messages, key)
+	}
+	return nil
+}
+
+// sortStoredMessages orders messages by creation time.
+func sortStoredMessages(messages []*StoredMessage) {
+	slices.SortStableFunc(messages, func(a, b *StoredMessage) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+// prunePending sorts messages and splits off the ones older than ttl, then the oldest ones
+// beyond maxPending. Zero disables a limit.
+func prunePending(messages []*StoredMessage, maxPending int, ttl time.Duration, now time.Time) (kept, dropped []*StoredMessage) {
+	sortStoredMessages(messages)
+	for len(messages) > 0 && (ttl > 0 && now.Sub(messages[0].CreatedAt) > ttl || maxPending > 0 && len(messages) > maxPending) {
+		dropped = append(dropped, messages[0])
+		messages = messages[1:]
+	}
+	return messages, dropped
+}
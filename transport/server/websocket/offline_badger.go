@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/vkviyu/nexus/database/embedded/badgerdb"
+)
+
+// DefaultOfflineKeyPrefix is the key prefix used by BadgerOfflineStore.
+var DefaultOfflineKeyPrefix = "nexus/offline/"
+
+// BadgerOfflineStore is an OfflineStore persisted in a badgerdb database.
+// Messages are stored under Prefix + endpointPath + "\x00" + connId + "\x00" + message ID.
+// Entries carry TTL, so badger removes expired messages by itself.
+type BadgerOfflineStore struct {
+	DB     *badgerdb.DB
+	Prefix string
+	// MaxPending and TTL limit the pending messages, see DefaultOfflineMaxPending.
+	MaxPending int
+	TTL        time.Duration
+}
+
+func NewBadgerOfflineStore(db *badgerdb.DB) *BadgerOfflineStore {
+	return &BadgerOfflineStore{
+		DB:         db,
+		Prefix:     DefaultOfflineKeyPrefix,
+		MaxPending: DefaultOfflineMaxPending,
+		TTL:        DefaultOfflineTTL,
+	}
+}
+
+func (s *BadgerOfflineStore) connPrefix(endpointPath EndpointPath, connId ConnId) string {
+	return s.Prefix + offlineKey(endpointPath, connId) + "\x00"
+}
+
+func (s *BadgerOfflineStore) Save(endpointPath EndpointPath, connId ConnId, msg *StoredMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	prefix := s.connPrefix(endpointPath, connId)
+	return s.DB.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry([]byte(prefix+msg.ID), data)
+		if s.TTL > 0 {
+			entry = entry.WithTTL(s.TTL)
+		}
+		if err := txn.SetEntry(entry); err != nil {
+			return err
+		}
+		if s.MaxPending <= 0 {
+			return nil
+		}
+		return s.trim(txn, prefix)
+	})
+}
+
+// trim drops the oldest messages under prefix beyond MaxPending.
+func (s *BadgerOfflineStore) trim(txn *badger.Txn, prefix string) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = []byte(prefix)
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	count := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		count++
+	}
+	it.Close()
+	if count <= s.MaxPending {
+		return nil
+	}
+
+	opts.PrefetchValues = true
+	it = txn.NewIterator(opts)
+	var messages []*StoredMessage
+	for it.Rewind(); it.Valid(); it.Next() {
+		msg := &StoredMessage{}
+		if err := it.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, msg)
+		}); err != nil {
+			it.Close()
+			return err
+		}
+		messages = append(messages, msg)
+	}
+	it.Close()
+	_, dropped := prunePending(messages, s.MaxPending, s.TTL, time.Now())
+	for _, msg := range dropped {
+		if err := txn.Delete([]byte(prefix + msg.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BadgerOfflineStore) Load(endpointPath EndpointPath, connId ConnId) ([]*StoredMessage, error) {
+	var messages []*StoredMessage
+	err := s.DB.ForEachByPrefix(s.connPrefix(endpointPath, connId), badger.DefaultIteratorOptions, func(item *badger.Item) error {
+		return item.Value(func(val []byte) error {
+			msg := &StoredMessage{}
+			if err := json.Unmarshal(val, msg); err != nil {
+				return err
+			}
+			messages = append(messages, msg)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages, _ = prunePending(messages, 0, s.TTL, time.Now())
+	return messages, nil
+}
+
+func (s *BadgerOfflineStore) Delete(endpointPath EndpointPath, connId ConnId, msgId string) error {
+	return s.DB.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(s.connPrefix(endpointPath, connId) + msgId))
+	})
+}
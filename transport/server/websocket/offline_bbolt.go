@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/vkviyu/nexus/database/embedded/bboltdb"
+	"go.etcd.io/bbolt"
+)
+
+// DefaultOfflineBucket is the root bucket used by BboltOfflineStore.
+var DefaultOfflineBucket = "nexus_offline"
+
+// BboltOfflineStore is an OfflineStore persisted in a bboltdb database.
+// Messages are kept in the nested buckets [Bucket, endpointPath, connId], keyed by message ID.
+// Expired messages are skipped by Load but stay on disk, call DeleteExpired periodically to
+// remove them, including those of connections that never come back.
+type BboltOfflineStore struct {
+	DB     *bboltdb.DB
+	Bucket string
+	// MaxPending and TTL limit the pending messages, see DefaultOfflineMaxPending.
+	MaxPending int
+	TTL        time.Duration
+}
+
+func NewBboltOfflineStore(db *bboltdb.DB) *BboltOfflineStore {
+	return &BboltOfflineStore{
+		DB:         db,
+		Bucket:     DefaultOfflineBucket,
+		MaxPending: DefaultOfflineMaxPending,
+		TTL:        DefaultOfflineTTL,
+	}
+}
+
+func (s *BboltOfflineStore) buckets(endpointPath EndpointPath, connId ConnId) []string {
+	return []string{s.Bucket, endpointPath, connId}
+}
+
+func (s *BboltOfflineStore) Save(endpointPath EndpointPath, connId ConnId, msg *StoredMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.DB.NestedUpdateTransaction(s.buckets(endpointPath, connId), func(tx *bbolt.Tx, b *bbolt.Bucket) error {
+		if err := b.Put([]byte(msg.ID), data); err != nil {
+			return err
+		}
+		if s.MaxPending <= 0 || bboltKeyCount(b) <= s.MaxPending {
+			return nil
+		}
+		messages, err := bboltMessages(b)
+		if err != nil {
+			return err
+		}
+		_, dropped := prunePending(messages, s.MaxPending, s.TTL, time.Now())
+		return deleteBboltMessages(b, dropped)
+	})
+}
+
+func (s *BboltOfflineStore) Load(endpointPath EndpointPath, connId ConnId) ([]*StoredMessage, error) {
+	var messages []*StoredMessage
+	err := s.DB.NestedViewTransaction(s.buckets(endpointPath, connId), func(tx *bbolt.Tx, b *bbolt.Bucket) error {
+		var err error
+		messages, err = bboltMessages(b)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages, _ = prunePending(messages, 0, s.TTL, time.Now())
+	return messages, nil
+}
+
+func (s *BboltOfflineStore) Delete(endpointPath EndpointPath, connId ConnId, msgId string) error {
+	return s.DB.NestedUpdateTransaction(s.buckets(endpointPath, connId), func(tx *bbolt.Tx, b *bbolt.Bucket) error {
+		return b.Delete([]byte(msgId))
+	})
+}
+
+// DeleteExpired removes the messages older than TTL at now, and the buckets of connections
+// left without pending messages.
+func (s *BboltOfflineStore) DeleteExpired(now time.Time) error {
+	return s.DB.NestedUpdateTransaction([]string{s.Bucket}, func(tx *bbolt.Tx, root *bbolt.Bucket) error {
+		return root.ForEachBucket(func(endpointPath []byte) error {
+			endpoint := root.Bucket(endpointPath)
+			// 遍历期间不修改，结束后再删除
+			expired := make(map[string][]*StoredMessage)
+			var empty [][]byte
+			err := endpoint.ForEachBucket(func(connId []byte) error {
+				messages, err := bboltMessages(endpoint.Bucket(connId))
+				if err != nil {
+					return err
+				}
+				kept, dropped := prunePending(messages, 0, s.TTL, now)
+				if len(kept) == 0 {
+					empty = append(empty, bytes.Clone(connId))
+				} else if len(dropped) > 0 {
+					expired[string(connId)] = dropped
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for connId, dropped := range expired {
+				if err := deleteBboltMessages(endpoint.Bucket([]byte(connId)), dropped); err != nil {
+					return err
+				}
+			}
+			for _, connId := range empty {
+				if err := endpoint.DeleteBucket(connId); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func bboltMessages(b *bbolt.Bucket) ([]*StoredMessage, error) {
+	var messages []*StoredMessage
+	err := b.ForEach(func(k, v []byte) error {
+		msg := &StoredMessage{}
+		if err := json.Unmarshal(v, msg); err != nil {
+			return err
+		}
+		messages = append(messages, msg)
+		return nil
+	})
+	return messages, err
+}
+
+// bboltKeyCount counts the keys of b, b.Stats() misses the writes of the current
+// transaction.
+func bboltKeyCount(b *bbolt.Bucket) int {
+	count := 0
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		count++
+	}
+	return count
+}
+
+func deleteBboltMessages(b *bbolt.Bucket, messages []*StoredMessage) error {
+	for _, msg := range messages {
+		if err := b.Delete([]byte(msg.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gorilla/websocket"
+	"github.com/vkviyu/nexus/database/embedded/badgerdb"
+	"github.com/vkviyu/nexus/database/embedded/bboltdb"
+	"go.etcd.io/bbolt"
+)
+
+func TestEndpointOfflineDelivery(t *testing.T) {
+	store := NewMemoryOfflineStore()
+	ep := NewEndpoint("/ws",
+		WithOfflineStore(store),
+		WithAuthFunc(func(r *http.Request) (bool, string) {
+			return true, r.URL.Query().Get("id")
+		}),
+	)
+
+	err := ep.SendMessage(&Message{Message: []byte(`{"text":"hi"}`), ConnIds: []ConnId{"alice"}, Reliable: true})
+	if err != nil {
+		t.Fatalf("send to offline connection failed: %v", err)
+	}
+
+	conn := dialTestEndpoint(t, ep, "?id=alice")
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	var frame DeliveryFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		t.Fatalf("invalid delivery frame %s: %v", data, err)
+	}
+	if frame.ID == "" || string(frame.Data) != `{"text":"hi"}` {
+		t.Fatalf("unexpected delivery frame: %s", data)
+	}
+
+	ack, _ := json.Marshal(AckFrame{Ack: frame.ID})
+	if err := conn.WriteMessage(websocket.TextMessage, ack); err != nil {
+		t.Fatalf("ack failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		pending, _ := store.Load("/ws", "alice")
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("acknowledged message is still pending")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEndpointOfflineStoreDirectMessages(t *testing.T) {
+	store := NewMemoryOfflineStore()
+	ep := NewEndpoint("/ws",
+		WithOfflineStore(store),
+		WithAuthFunc(func(r *http.Request) (bool, string) {
+			return true, r.URL.Query().Get("id")
+		}),
+	)
+	conn := dialTestEndpoint(t, ep, "?id=alice")
+	waitForConn(t, ep, "alice")
+
+	// 非 Reliable 消息（如 Router 回复）直接写出，不封装、不存储
+	if err := ep.SendMessage(&Message{Message: []byte(`{"type":"pong"}`), ConnIds: []ConnId{"alice"}}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != `{"type":"pong"}` {
+		t.Fatalf("expected the raw message, got %s, %v", data, err)
+	}
+	if pending, _ := store.Load("/ws", "alice"); len(pending) != 0 {
+		t.Errorf("expected no pending messages, got %d", len(pending))
+	}
+	if err := ep.SendMessage(&Message{Message: []byte("hi"), ConnIds: []ConnId{"bob"}}); err == nil {
+		t.Error("expected ConnNotFoundError for an absent connection")
+	}
+
+	// 只有完全符合 AckFrame 形状的消息才被当作确认
+	for _, message := range []string{`{"ack":"x","text":"hi"}`, `{"ack":"x"} {}`, `{"ack":1}`} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case msg := <-ep.MsgChan:
+			if string(msg.Message.Message) != message {
+				t.Errorf("expected %s, got %s", message, msg.Message.Message)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s to reach MsgChan", message)
+		}
+	}
+}
+
+func TestOfflineStores(t *testing.T) {
+	bolt, err := bboltdb.Open(filepath.Join(t.TempDir(), "offline.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("open bbolt failed: %v", err)
+	}
+	defer bolt.Close()
+	badgerDir := t.TempDir()
+	badgerDB, err := badgerdb.Open(badgerDir, badger.DefaultOptions(badgerDir).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger failed: %v", err)
+	}
+	defer badgerDB.Close()
+
+	stores := map[string]OfflineStore{
+		"memory": NewMemoryOfflineStore(),
+		"bbolt":  NewBboltOfflineStore(bolt),
+		"badger": NewBadgerOfflineStore(badgerDB),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			first := newStoredMessage(&Message{MessageType: TextMessage, Message: []byte("first")})
+			second := newStoredMessage(&Message{MessageType: BinaryMessage, Message: []byte("second")})
+			second.CreatedAt = first.CreatedAt.Add(time.Millisecond)
+			for _, msg := range []*StoredMessage{second, first} {
+				if err := store.Save("/ws", "alice", msg); err != nil {
+					t.Fatalf("save failed: %v", err)
+				}
+			}
+
+			pending, err := store.Load("/ws", "alice")
+			if err != nil {
+				t.Fatalf("load failed: %v", err)
+			}
+			if len(pending) != 2 || pending[0].ID != first.ID || string(pending[1].Message) != "second" {
+				t.Fatalf("unexpected pending messages: %+v", pending)
+			}
+
+			if err := store.Delete("/ws", "alice", first.ID); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			pending, _ = store.Load("/ws", "alice")
+			if len(pending) != 1 || pending[0].ID != second.ID {
+				t.Fatalf("unexpected pending messages after delete: %+v", pending)
+			}
+			if other, _ := store.Load("/ws", "bob"); len(other) != 0 {
+				t.Errorf("expected no messages for another connection, got %d", len(other))
+			}
+		})
+	}
+}
+
+func TestOfflineStoreLimits(t *testing.T) {
+	bolt, err := bboltdb.Open(filepath.Join(t.TempDir(), "offline.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("open bbolt failed: %v", err)
+	}
+	defer bolt.Close()
+	badgerDir := t.TempDir()
+	badgerDB, err := badgerdb.Open(badgerDir, badger.DefaultOptions(badgerDir).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger failed: %v", err)
+	}
+	defer badgerDB.Close()
+
+	memory := NewMemoryOfflineStore()
+	memory.MaxPending, memory.TTL = 2, time.Hour
+	bboltStore := NewBboltOfflineStore(bolt)
+	bboltStore.MaxPending, bboltStore.TTL = 2, time.Hour
+	badgerStore := NewBadgerOfflineStore(badgerDB)
+	badgerStore.MaxPending, badgerStore.TTL = 2, time.Hour
+
+	stores := map[string]OfflineStore{
+		"memory": memory,
+		"bbolt":  bboltStore,
+		"badger": badgerStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			var ids []string
+			for i := range 3 {
+				msg := newStoredMessage(&Message{MessageType: TextMessage, Message: []byte("hi")})
+				msg.CreatedAt = now.Add(time.Duration(i) * time.Millisecond)
+				ids = append(ids, msg.ID)
+				if err := store.Save("/ws", "alice", msg); err != nil {
+					t.Fatalf("save failed: %v", err)
+				}
+			}
+			pending, err := store.Load("/ws", "alice")
+			if err != nil {
+				t.Fatalf("load failed: %v", err)
+			}
+			if len(pending) != 2 || pending[0].ID != ids[1] || pending[1].ID != ids[2] {
+				t.Fatalf("expected the 2 newest messages, got %+v", pending)
+			}
+
+			expired := newStoredMessage(&Message{MessageType: TextMessage, Message: []byte("old")})
+			expired.CreatedAt = now.Add(-2 * time.Hour)
+			if err := store.Save("/ws", "bob", expired); err != nil {
+				t.Fatalf("save failed: %v", err)
+			}
+			if pending, _ := store.Load("/ws", "bob"); len(pending) != 0 {
+				t.Errorf("expected expired messages to be skipped, got %+v", pending)
+			}
+		})
+	}
+
+	if err := bboltStore.DeleteExpired(time.Now()); err != nil {
+		t.Fatalf("delete expired failed: %v", err)
+	}
+	err = bolt.NestedViewTransaction([]string{DefaultOfflineBucket, "/ws"}, func(tx *bbolt.Tx, b *bbolt.Bucket) error {
+		if b.Bucket([]byte("bob")) != nil || b.Bucket([]byte("alice")) == nil {
+			t.Error("expected only the bucket of the expired connection to be removed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,137 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitAction is what an endpoint does with a message that violates its RateLimit.
+type RateLimitAction int
+
+const (
+	// RateLimitDrop silently drops the message.
+	RateLimitDrop RateLimitAction = iota
+	// RateLimitWarn drops the message and sends an error envelope to the client.
+	RateLimitWarn
+	// RateLimitClose closes the connection with 1008 (policy violation).
+	RateLimitClose
+)
+
+// Error codes sent with RateLimitWarn, in the same envelope format as Router error replies.
+const (
+	ErrorCodeRateLimited     = "rate_limited"
+	ErrorCodeMessageTooLarge = "message_too_large"
+)
+
+// RateLimit limits the messages read from each connection with a token bucket.
+type RateLimit struct {
+	// Rate is the number of messages per second allowed on average, 0 disables the rate check.
+	Rate float64
+	// Burst is the bucket size, at least 1.
+	Burst int
+	// MaxMessageSize rejects larger messages with Action, 0 disables the size check.
+	// Unlike the endpoint's ReadLimit, which always closes with 1009, the message is handled by Action.
+	MaxMessageSize int
+	Action         RateLimitAction
+}
+
+// RateLimitStats counts the messages checked by an endpoint's RateLimit.
+type RateLimitStats struct {
+	Allowed   uint64 `json:"allowed"`
+	Limited   uint64 `json:"limited"`
+	Oversized uint64 `json:"oversized"`
+	Warned    uint64 `json:"warned"`
+	Closed    uint64 `json:"closed"`
+}
+
+type rateLimitCounters struct {
+	allowed, limited, oversized, warned, closed atomic.Uint64
+}
+
+func WithRateLimit(limit RateLimit) EndpointOption {
+	return func(e *Endpoint) {
+		e.RateLimit = &limit
+	}
+}
+
+// RateLimitStats returns a snapshot of the rate limit counters.
+func (e *Endpoint) RateLimitStats() RateLimitStats {
+	return RateLimitStats{
+		Allowed:   e.rateLimitCounters.allowed.Load(),
+		Limited:   e.rateLimitCounters.limited.Load(),
+		Oversized: e.rateLimitCounters.oversized.Load(),
+		Warned:    e.rateLimitCounters.warned.Load(),
+		Closed:    e.rateLimitCounters.closed.Load(),
+	}
+}
+
+// checkRateLimit reports whether the message may be processed, and whether the
+// connection has been closed because of it.
+func (e *Endpoint) checkRateLimit(conn *SafeConn, message []byte) (allow bool, closed bool) {
+	limit := e.RateLimit
+	code, reason := "", ""
+	switch {
+	case limit.MaxMessageSize > 0 && len(message) > limit.MaxMessageSize:
+		e.rateLimitCounters.oversized.Add(1)
+		code, reason = ErrorCodeMessageTooLarge, "message too large"
+	case limit.Rate > 0 && !conn.limiter.allow():
+		e.rateLimitCounters.limited.Add(1)
+		code, reason = ErrorCodeRateLimited, "rate limit exceeded"
+	default:
+		e.rateLimitCounters.allowed.Add(1)
+		return true, false
+	}
+
+	switch limit.Action {
+	case RateLimitWarn:
+		e.rateLimitCounters.warned.Add(1)
+		warning, _ := json.Marshal(&Envelope{
+			Type:  ErrorEnvelopeType,
+			Error: &EnvelopeError{Code: code, Message: reason},
+		})
+		if err := conn.SafeWriteMessage(int(TextMessage), warning); err != nil {
+			e.ReadErrorFunc(err)
+		}
+	case RateLimitClose:
+		e.rateLimitCounters.closed.Add(1)
+		writeCloseFrame(conn, ClosePolicyViolation, reason)
+		return false, true
+	}
+	return false, false
+}
+
+// tokenBucket is a token bucket refilled continuously at rate tokens per second.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRateLimitWarn(t *testing.T) {
+	ep := NewEndpoint("/ws", WithRateLimit(RateLimit{Rate: 0.001, Burst: 1, Action: RateLimitWarn}))
+	conn := dialTestEndpoint(t, ep, "")
+
+	for i := 0; i < 2; i++ {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if msg := <-ep.GetMsgChan(); string(msg.Message.Message) != "ping" {
+		t.Errorf("expected first message to pass, got %s", msg.Message.Message)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Error == nil || env.Error.Code != ErrorCodeRateLimited {
+		t.Fatalf("expected rate_limited warning, got %s", data)
+	}
+	if stats := ep.RateLimitStats(); stats.Allowed != 1 || stats.Limited != 1 || stats.Warned != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRateLimitCloseOversized(t *testing.T) {
+	ep := NewEndpoint("/ws", WithRateLimit(RateLimit{MaxMessageSize: 4, Action: RateLimitClose}))
+	conn := dialTestEndpoint(t, ep, "")
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("too large")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != ClosePolicyViolation {
+		t.Fatalf("expected policy violation close, got %v", err)
+	}
+	if stats := ep.RateLimitStats(); stats.Oversized != 1 || stats.Closed != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
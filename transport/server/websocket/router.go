@@ -0,0 +1,226 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/vkviyu/nexus/utils/jsonutil"
+)
+
+// Envelope is the JSON message structure handled by Router.
+//
+//	{"type": "chat.send", "id": "42", "data": {"text": "hello"}}
+//
+// Type selects the handler. ID is optional: when present, replies carry the same ID
+// so that clients can correlate RPC-style requests with their responses.
+type Envelope struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error *EnvelopeError  `json:"error,omitempty"`
+}
+
+// EnvelopeError is the error part of an error reply.
+// Handlers can return an *EnvelopeError to control the code sent to the client.
+type EnvelopeError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *EnvelopeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Standard error codes used in error replies.
+const (
+	ErrorCodeInvalidEnvelope = "invalid_envelope"
+	ErrorCodeUnknownType     = "unknown_type"
+	ErrorCodeInvalidPayload  = "invalid_payload"
+	ErrorCodeHandlerError    = "handler_error"
+)
+
+// ErrorEnvelopeType is the envelope type of error replies.
+var ErrorEnvelopeType = "error"
+
+// RouteContext carries the incoming envelope and the connection it came from.
+type RouteContext struct {
+	context.Context
+	Endpoint *Endpoint
+	ConnId   ConnId
+	Envelope *Envelope
+}
+
+// Reply sends data back to the sender using the type and ID of the incoming envelope.
+func (c *RouteContext) Reply(data any) error {
+	return c.send(&Envelope{Type: c.Envelope.Type, ID: c.Envelope.ID}, data)
+}
+
+// ReplyError sends an error reply correlated with the incoming envelope.
+func (c *RouteContext) ReplyError(code, message string) error {
+	return c.send(&Envelope{
+		Type:  ErrorEnvelopeType,
+		ID:    c.Envelope.ID,
+		Error: &EnvelopeError{Code: code, Message: message},
+	}, nil)
+}
+
+// Send pushes an uncorrelated envelope of the given type to the sender.
+func (c *RouteContext) Send(msgType string, data any) error {
+	return c.send(&Envelope{Type: msgType}, data)
+}
+
+func (c *RouteContext) send(envelope *Envelope, data any) error {
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		envelope.Data = raw
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return c.Endpoint.SendMessage(&Message{
+		MessageType: TextMessage,
+		Message:     payload,
+		ConnIds:     []ConnId{c.ConnId},
+	})
+}
+
+// RouteHandler handles a routed envelope.
+// A returned error is sent to the client as an error reply.
+type RouteHandler func(ctx *RouteContext) error
+
+// Router dispatches JSON envelopes received by an Endpoint to handlers registered per message type.
+//
+// Example:
+//
+//	router := websocket.NewRouter(endpoint)
+//	websocket.HandleRequest(router, "user.get", func(ctx *websocket.RouteContext, req *GetUserReq) (*User, error) {
+//		return findUser(req.ID)
+//	})
+//	go router.Serve(stopctx)
+type Router struct {
+	endpoint *Endpoint
+	routes   map[string]RouteHandler
+	mu       sync.RWMutex
+
+	// ErrorFunc is called when a reply cannot be delivered. Defaults to DefaultReadErrorFunc.
+	ErrorFunc func(err error)
+}
+
+func NewRouter(endpoint *Endpoint) *Router {
+	return &Router{
+		endpoint:  endpoint,
+		routes:    make(map[string]RouteHandler),
+		ErrorFunc: DefaultReadErrorFunc,
+	}
+}
+
+// HandleFunc registers a handler working on the raw envelope.
+func (r *Router) HandleFunc(msgType string, handler RouteHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[msgType] = handler
+}
+
+// Handle registers a handler whose payload is decoded into T and validated.
+// Invalid payloads are answered with an invalid_payload error reply.
+func Handle[T any](r *Router, msgType string, fn func(ctx *RouteContext, data *T) error) {
+	r.HandleFunc(msgType, func(ctx *RouteContext) error {
+		data, err := decodePayload[T](ctx.Envelope.Data)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, data)
+	})
+}
+
+// HandleRequest registers an RPC-style handler: the returned response is sent back
+// with the type and ID of the request.
+func HandleRequest[Req, Resp any](r *Router, msgType string, fn func(ctx *RouteContext, req *Req) (*Resp, error)) {
+	r.HandleFunc(msgType, func(ctx *RouteContext) error {
+		req, err := decodePayload[Req](ctx.Envelope.Data)
+		if err != nil {
+			return err
+		}
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return err
+		}
+		return ctx.Reply(resp)
+	})
+}
+
+func decodePayload[T any](data json.RawMessage) (*T, error) {
+	if len(data) == 0 {
+		data = json.RawMessage("null")
+	}
+	result, err := jsonutil.ParseJsonAndValidate[T](data)
+	if err != nil {
+		return nil, &EnvelopeError{Code: ErrorCodeInvalidPayload, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// Dispatch routes a single message received by the endpoint.
+func (r *Router) Dispatch(ctx context.Context, msg *EndpointMessage) {
+	if len(msg.ConnIds) == 0 {
+		return
+	}
+	routeCtx := &RouteContext{
+		Context:  ctx,
+		Endpoint: r.endpoint,
+		ConnId:   msg.ConnIds[0],
+		Envelope: &Envelope{},
+	}
+	if err := json.Unmarshal(msg.Message.Message, routeCtx.Envelope); err != nil || routeCtx.Envelope.Type == "" {
+		r.replyError(routeCtx, &EnvelopeError{Code: ErrorCodeInvalidEnvelope, Message: "message is not a valid envelope"})
+		return
+	}
+
+	r.mu.RLock()
+	handler, ok := r.routes[routeCtx.Envelope.Type]
+	r.mu.RUnlock()
+	if !ok {
+		r.replyError(routeCtx, &EnvelopeError{
+			Code:    ErrorCodeUnknownType,
+			Message: fmt.Sprintf("unknown message type: %s", routeCtx.Envelope.Type),
+		})
+		return
+	}
+	if err := handler(routeCtx); err != nil {
+		r.replyError(routeCtx, err)
+	}
+}
+
+func (r *Router) replyError(ctx *RouteContext, err error) {
+	var envelopeErr *EnvelopeError
+	if !errors.As(err, &envelopeErr) {
+		envelopeErr = &EnvelopeError{Code: ErrorCodeHandlerError, Message: err.Error()}
+	}
+	if sendErr := ctx.ReplyError(envelopeErr.Code, envelopeErr.Message); sendErr != nil {
+		r.ErrorFunc(sendErr)
+	}
+}
+
+// Serve consumes the endpoint's MsgChan and dispatches every message until ctx is done
+// or the channel is closed. Handlers run sequentially in the calling goroutine.
+func (r *Router) Serve(ctx context.Context) {
+	msgChan := r.endpoint.GetMsgChan()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgChan:
+			if !ok {
+				return
+			}
+			r.Dispatch(ctx, msg)
+		}
+	}
+}
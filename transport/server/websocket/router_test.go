@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+type echoReq struct {
+	Text string `json:"text" validate:"required"`
+}
+
+type echoResp struct {
+	Text string `json:"text"`
+}
+
+func dialTestEndpoint(t *testing.T, ep *Endpoint, query string) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(ep)
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+query, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRouter(t *testing.T) {
+	ep := NewEndpoint("/ws")
+	router := NewRouter(ep)
+	HandleRequest(router, "echo", func(ctx *RouteContext, req *echoReq) (*echoResp, error) {
+		return &echoResp{Text: req.Text}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go router.Serve(ctx)
+
+	conn := dialTestEndpoint(t, ep, "")
+
+	tests := []struct {
+		name    string
+		request string
+		check   func(env *Envelope) bool
+	}{
+		{
+			name:    "rpc reply",
+			request: `{"type":"echo","id":"1","data":{"text":"hi"}}`,
+			check: func(env *Envelope) bool {
+				return env.Type == "echo" && env.ID == "1" && string(env.Data) == `{"text":"hi"}`
+			},
+		},
+		{
+			name:    "invalid payload",
+			request: `{"type":"echo","id":"2","data":{}}`,
+			check: func(env *Envelope) bool {
+				return env.Type == ErrorEnvelopeType && env.ID == "2" && env.Error.Code == ErrorCodeInvalidPayload
+			},
+		},
+		{
+			name:    "unknown type",
+			request: `{"type":"nope","id":"3"}`,
+			check: func(env *Envelope) bool {
+				return env.Error != nil && env.Error.Code == ErrorCodeUnknownType
+			},
+		},
+		{
+			name:    "invalid envelope",
+			request: `not json`,
+			check: func(env *Envelope) bool {
+				return env.Error != nil && env.Error.Code == ErrorCodeInvalidEnvelope
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.request)); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			var env Envelope
+			if err := json.Unmarshal(data, &env); err != nil {
+				t.Fatalf("invalid reply %s: %v", data, err)
+			}
+			if !tt.check(&env) {
+				t.Errorf("unexpected reply: %s", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package websocket
+
+import (
+	"cmp"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// EndpointStats is a snapshot of an endpoint's activity since it was created.
+type EndpointStats struct {
+	EndpointPath      EndpointPath `json:"endpointPath"`
+	ActiveConnections int          `json:"activeConnections"`
+	TotalConnections  uint64       `json:"totalConnections"`
+	MessagesIn        uint64       `json:"messagesIn"`
+	MessagesOut       uint64       `json:"messagesOut"`
+	BytesIn           uint64       `json:"bytesIn"`
+	BytesOut          uint64       `json:"bytesOut"`
+	// Errors counts failed writes and reads that did not end with a normal close.
+	Errors uint64 `json:"errors"`
+	// AvgConnectionSeconds is the average lifetime of the connections closed so far.
+	AvgConnectionSeconds float64         `json:"avgConnectionSeconds"`
+	RateLimit            *RateLimitStats `json:"rateLimit,omitempty"`
+}
+
+// ConnStats is a snapshot of a single connection.
+type ConnStats struct {
+	ConnId          ConnId    `json:"connId"`
+	Subprotocol     string    `json:"subprotocol,omitempty"`
+	Origin          string    `json:"origin,omitempty"`
+	RemoteAddr      string    `json:"remoteAddr"`
+	ConnectedAt     time.Time `json:"connectedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	MessagesIn      uint64    `json:"messagesIn"`
+	MessagesOut     uint64    `json:"messagesOut"`
+	BytesIn         uint64    `json:"bytesIn"`
+	BytesOut        uint64    `json:"bytesOut"`
+}
+
+type trafficCounters struct {
+	messagesIn, messagesOut, bytesIn, bytesOut, errors atomic.Uint64
+}
+
+func (c *trafficCounters) recordIn(n int) {
+	c.messagesIn.Add(1)
+	c.bytesIn.Add(uint64(n))
+}
+
+func (c *trafficCounters) recordOut(n int, err error) {
+	if err != nil {
+		c.errors.Add(1)
+		return
+	}
+	c.messagesOut.Add(1)
+	c.bytesOut.Add(uint64(n))
+}
+
+type connectionCounters struct {
+	total, closed       atomic.Uint64
+	closedDurationNanos atomic.Int64
+}
+
+// recordIn counts a message read from the connection.
+func (sc *SafeConn) recordIn(n int) {
+	sc.counters.recordIn(n)
+	if sc.endpointCounters != nil {
+		sc.endpointCounters.recordIn(n)
+	}
+}
+
+// recordOut counts a message written to the connection.
+func (sc *SafeConn) recordOut(n int, err error) {
+	sc.counters.recordOut(n, err)
+	if sc.endpointCounters != nil {
+		sc.endpointCounters.recordOut(n, err)
+	}
+}
+
+// recordReadError counts read errors other than a normal close by the client.
+func (e *Endpoint) recordReadError(err error) {
+	if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+		e.traffic.errors.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the endpoint's counters.
+func (e *Endpoint) Stats() EndpointStats {
+	stats := EndpointStats{
+		EndpointPath:      e.EndpointPath,
+		ActiveConnections: e.GetConnCount(),
+		TotalConnections:  e.connections.total.Load(),
+		MessagesIn:        e.traffic.messagesIn.Load(),
+		MessagesOut:       e.traffic.messagesOut.Load(),
+		BytesIn:           e.traffic.bytesIn.Load(),
+		BytesOut:          e.traffic.bytesOut.Load(),
+		Errors:            e.traffic.errors.Load(),
+	}
+	if closed := e.connections.closed.Load(); closed > 0 {
+		stats.AvgConnectionSeconds = time.Duration(e.connections.closedDurationNanos.Load() / int64(closed)).Seconds()
+	}
+	if e.RateLimit != nil {
+		rateLimitStats := e.RateLimitStats()
+		stats.RateLimit = &rateLimitStats
+	}
+	return stats
+}
+
+// ConnStats returns a snapshot of every active connection, oldest first.
+func (e *Endpoint) ConnStats() []ConnStats {
+	e.connMu.RLock()
+	conns := make([]*SafeConn, 0, len(e.ConnMap))
+	for _, conn := range e.ConnMap {
+		conns = append(conns, conn)
+	}
+	e.connMu.RUnlock()
+
+	now := time.Now()
+	stats := make([]ConnStats, 0, len(conns))
+	for _, conn := range conns {
+		stats = append(stats, ConnStats{
+			ConnId:          conn.Meta.ConnId,
+			Subprotocol:     conn.Meta.Subprotocol,
+			Origin:          conn.Meta.Origin,
+			RemoteAddr:      conn.Meta.RemoteAddr,
+			ConnectedAt:     conn.Meta.ConnectedAt,
+			DurationSeconds: now.Sub(conn.Meta.ConnectedAt).Seconds(),
+			MessagesIn:      conn.counters.messagesIn.Load(),
+			MessagesOut:     conn.counters.messagesOut.Load(),
+			BytesIn:         conn.counters.bytesIn.Load(),
+			BytesOut:        conn.counters.bytesOut.Load(),
+		})
+	}
+	slices.SortFunc(stats, func(a, b ConnStats) int {
+		return a.ConnectedAt.Compare(b.ConnectedAt)
+	})
+	return stats
+}
+
+// Stats returns the stats of every endpoint, including MessageEndpoints, sorted by path.
+func (s *Manager) Stats() []EndpointStats {
+	stats := make([]EndpointStats, 0, len(s.EndpointMap)+len(s.MessageEndpoints))
+	for _, endpoint := range s.EndpointMap {
+		stats = append(stats, endpoint.Stats())
+	}
+	for _, endpoint := range s.MessageEndpoints {
+		stats = append(stats, endpoint.Stats())
+	}
+	slices.SortFunc(stats, func(a, b EndpointStats) int {
+		return cmp.Compare(a.EndpointPath, b.EndpointPath)
+	})
+	return stats
+}
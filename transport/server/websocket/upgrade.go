@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultReadLimit is the maximum message size in bytes accepted by an endpoint
+// when no read limit is configured. Larger messages close the connection with 1009.
+var DefaultReadLimit int64 = 1 << 20
+
+// WithAllowedOrigins restricts the origins allowed to open a connection.
+//
+// A pattern is either a full origin ("https://app.example.com"), a host matching any
+// scheme ("app.example.com"), a wildcard subdomain ("https://*.example.com" or
+// "*.example.com", which does not match example.com itself), or "*" to allow any origin.
+// A pattern without a port matches the host on any port; with a port ("localhost:3000"),
+// only that port, the default port of the scheme being used when the origin has none.
+// Without allowed origins, only same-origin requests and requests without an Origin
+// header (non-browser clients) are accepted.
+func WithAllowedOrigins(origins ...string) EndpointOption {
+	return func(e *Endpoint) {
+		e.AllowedOrigins = origins
+	}
+}
+
+// WithSubprotocols sets the supported subprotocols in order of preference.
+// The negotiated subprotocol is available in SafeConn.Meta.
+func WithSubprotocols(subprotocols ...string) EndpointOption {
+	return func(e *Endpoint) {
+		e.Subprotocols = subprotocols
+	}
+}
+
+// WithReadLimit sets the maximum size of a received message in bytes.
+// A negative limit disables the check.
+func WithReadLimit(limit int64) EndpointOption {
+	return func(e *Endpoint) {
+		e.ReadLimit = limit
+	}
+}
+
+// WithCompression enables negotiation of permessage-deflate compression (RFC 7692).
+func WithCompression(enabled bool) EndpointOption {
+	return func(e *Endpoint) {
+		e.EnableCompression = enabled
+	}
+}
+
+// newUpgradeFunc builds the UpgradeFunc used when no custom one is set, from the current
+// AllowedOrigins, Subprotocols and EnableCompression.
+func (e *Endpoint) newUpgradeFunc() UpgraderFunc {
+	upgrader := &Upgrader{
+		ReadBufferSize:    DefaultUpgrader.ReadBufferSize,
+		WriteBufferSize:   DefaultUpgrader.WriteBufferSize,
+		Subprotocols:      e.Subprotocols,
+		EnableCompression: e.EnableCompression,
+	}
+	if len(e.AllowedOrigins) > 0 {
+		origins := e.AllowedOrigins
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || OriginAllowed(origin, origins)
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) (*WebSocketConn, error) {
+		return upgrader.Upgrade(w, r, nil)
+	}
+}
+
+// OriginAllowed reports whether origin matches one of the patterns, see WithAllowedOrigins.
+func OriginAllowed(origin string, patterns []string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme, host, port := strings.ToLower(u.Scheme), strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		port = defaultPorts[scheme]
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" {
+			return true
+		}
+		patternHost := pattern
+		if patternScheme, rest, ok := strings.Cut(pattern, "://"); ok {
+			if patternScheme != scheme {
+				continue
+			}
+			patternHost = rest
+		}
+		patternHost, patternPort := splitPatternPort(patternHost)
+		if patternPort != "" && patternPort != port {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(patternHost, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if patternHost == host {
+			return true
+		}
+	}
+	return false
+}
+
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// splitPatternPort splits the optional port off the host of an origin pattern.
+func splitPatternPort(hostPort string) (host, port string) {
+	i := strings.LastIndex(hostPort, ":")
+	if i < 0 || strings.Contains(hostPort[i:], "]") {
+		return strings.Trim(hostPort, "[]"), ""
+	}
+	return strings.Trim(hostPort[:i], "[]"), hostPort[i+1:]
+}
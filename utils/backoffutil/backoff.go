@@ -0,0 +1,46 @@
+// Package backoffutil computes retry and reconnection delays, shared by the HTTP and
+// WebSocket clients and the server-side brokers.
+package backoffutil
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff computes exponentially growing delays with random jitter.
+//
+// The delay of attempt n (starting at 0) is Initial * Multiplier^n, capped at Max,
+// then randomized by ±Jitter (a fraction between 0 and 1).
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// DefaultBackoff is used when a Backoff is left zero.
+var DefaultBackoff = Backoff{
+	Initial:    500 * time.Millisecond,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Delay returns the delay before the given retry attempt.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Initial <= 0 {
+		b = DefaultBackoff
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = 1
+	}
+	delay := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
@@ -133,11 +133,9 @@ func (g *contractGenerator) generate() string {
 	sb.WriteString("// Code generated by nexus genutil. DO NOT EDIT.\n")
 	fmt.Fprintf(&sb, "package %s\n\n", g.cfg.PackageName)
 
-	sb.WriteString("import (\n")
-	sb.WriteString("\t\"bytes\"\n")
-	sb.WriteString("\t\"encoding/json\"\n")
-	sb.WriteString("\t\"io\"\n")
-	sb.WriteString(")\n\n")
+	if g.cfg.PackageName != clientPackageName {
+		fmt.Fprintf(&sb, "import \"%s\"\n\n", clientImportPath)
+	}
 
 	var structs []string
 	g.isRequest = true
@@ -389,6 +387,20 @@ func (g *contractGenerator) getValueFromPath(path string) any {
 	return current
 }
 
+const (
+	clientPackageName = "client"
+	clientImportPath  = "github.com/vkviyu/nexus/transport/client"
+)
+
+// clientRef qualifies an identifier of the client package unless the code is generated
+// into that package.
+func (g *contractGenerator) clientRef(name string) string {
+	if g.cfg.PackageName == clientPackageName {
+		return name
+	}
+	return clientPackageName + "." + name
+}
+
 func (g *contractGenerator) generateContractFunc(queryStructName, reqStructName, respStructName string) string {
 	var sb strings.Builder
 
@@ -426,29 +438,27 @@ func (g *contractGenerator) generateDefaultFuncParams(sb *strings.Builder, query
 		params = append(params, "body *"+reqStructName)
 	}
 	sb.WriteString(strings.Join(params, ", "))
-	fmt.Fprintf(sb, ") *Contract[%s] {\n", responseType)
+	fmt.Fprintf(sb, ") *%s[%s] {\n", g.clientRef("Contract"), responseType)
 
-	urlExpr := g.buildURLExpr(pathParams)
-	fmt.Fprintf(sb, "\treturn &Contract[%s]{\n", responseType)
-	sb.WriteString("\t\tURL:    " + urlExpr + ",\n")
+	fmt.Fprintf(sb, "\treturn &%s[%s]{\n", g.clientRef("Contract"), responseType)
+	sb.WriteString("\t\tURL:    " + g.buildURLExpr() + ",\n")
+	if len(pathParams) > 0 {
+		sb.WriteString("\t\tPathParams: " + buildParamsExpr(pathParams, nil) + ",\n")
+	}
+	if queryStructName != "" {
+		sb.WriteString("\t\tQuery: query,\n")
+	}
 
 	if g.def.Request.Meta.Method != "" && g.def.Request.Meta.Method != "GET" {
 		sb.WriteString("\t\tMethod: \"" + g.def.Request.Meta.Method + "\",\n")
 	}
 
 	if reqStructName != "" {
-		sb.WriteString("\t\tBody:   toJSONReader(body),\n")
+		sb.WriteString("\t\tRequestBody: " + g.clientRef("JSONBody") + "(body),\n")
 	}
 
 	sb.WriteString("\t}\n")
-	sb.WriteString("}\n\n")
-
-	if reqStructName != "" {
-		sb.WriteString("func toJSONReader(v any) io.Reader {\n")
-		sb.WriteString("\tdata, _ := json.Marshal(v)\n")
-		sb.WriteString("\treturn bytes.NewReader(data)\n")
-		sb.WriteString("}\n")
-	}
+	sb.WriteString("}\n")
 }
 
 func (g *contractGenerator) generateCustomFuncParams(sb *strings.Builder, responseType string, pathParams []string) {
@@ -460,7 +470,7 @@ func (g *contractGenerator) generateCustomFuncParams(sb *strings.Builder, respon
 		params = append(params, p.Name+" *"+p.Type)
 	}
 	sb.WriteString(strings.Join(params, ", "))
-	fmt.Fprintf(sb, ") *Contract[%s] {\n", responseType)
+	fmt.Fprintf(sb, ") *%s[%s] {\n", g.clientRef("Contract"), responseType)
 
 	// Analyze struct fields to find path/query/body sources
 	pathSources := make(map[string]string)  // pathParam -> "paramName.FieldName"
@@ -502,105 +512,54 @@ func (g *contractGenerator) generateCustomFuncParams(sb *strings.Builder, respon
 		}
 	}
 
-	// Build URL expression with custom sources
-	urlExpr := g.buildURLExprWithSources(pathParams, pathSources)
-	fmt.Fprintf(sb, "\treturn &Contract[%s]{\n", responseType)
-	sb.WriteString("\t\tURL:    " + urlExpr + ",\n")
+	fmt.Fprintf(sb, "\treturn &%s[%s]{\n", g.clientRef("Contract"), responseType)
+	sb.WriteString("\t\tURL:    " + g.buildURLExpr() + ",\n")
+	if len(pathParams) > 0 {
+		sb.WriteString("\t\tPathParams: " + buildParamsExpr(pathParams, pathSources) + ",\n")
+	}
+	if len(querySources) > 0 {
+		queryKeys := make([]string, 0, len(querySources))
+		for k := range querySources {
+			queryKeys = append(queryKeys, k)
+		}
+		sort.Strings(queryKeys)
+		sb.WriteString("\t\tQuery: " + buildParamsExpr(queryKeys, querySources) + ",\n")
+	}
 
 	if g.def.Request.Meta.Method != "" && g.def.Request.Meta.Method != "GET" {
 		sb.WriteString("\t\tMethod: \"" + g.def.Request.Meta.Method + "\",\n")
 	}
 
 	if bodyParam != "" {
-		sb.WriteString("\t\tBody:   toJSONReader(" + bodyParam + "),\n")
+		sb.WriteString("\t\tRequestBody: " + g.clientRef("JSONBody") + "(" + bodyParam + "),\n")
 	}
 
 	sb.WriteString("\t}\n")
-	sb.WriteString("}\n\n")
-
-	if bodyParam != "" {
-		sb.WriteString("func toJSONReader(v any) io.Reader {\n")
-		sb.WriteString("\tdata, _ := json.Marshal(v)\n")
-		sb.WriteString("\treturn bytes.NewReader(data)\n")
-		sb.WriteString("}\n")
-	}
+	sb.WriteString("}\n")
 }
 
-func (g *contractGenerator) buildURLExprWithSources(pathParams []string, pathSources map[string]string) string {
-	path := g.def.Request.Meta.Path
+// buildURLExpr returns the URL of the contract with its {name} placeholders kept, they are
+// filled by client.Do from PathParams.
+func (g *contractGenerator) buildURLExpr() string {
 	baseURL := g.cfg.BaseURLConst
-
 	if g.def.Request.Meta.BaseURL != "" {
 		baseURL = fmt.Sprintf("\"%s\"", g.def.Request.Meta.BaseURL)
 	}
-
-	if len(pathParams) == 0 {
-		return fmt.Sprintf("%s + \"%s\"", baseURL, path)
-	}
-
-	var parts []string
-	remaining := path
-	for _, p := range pathParams {
-		placeholder := "{" + p + "}"
-		idx := strings.Index(remaining, placeholder)
-		if idx == -1 {
-			continue
-		}
-		if idx > 0 {
-			parts = append(parts, fmt.Sprintf("\"%s\"", remaining[:idx]))
-		}
-		// Use custom source if available, otherwise use param directly
-		if source, ok := pathSources[p]; ok {
-			parts = append(parts, source)
-		} else {
-			parts = append(parts, p)
-		}
-		remaining = remaining[idx+len(placeholder):]
-	}
-	if remaining != "" {
-		parts = append(parts, fmt.Sprintf("\"%s\"", remaining))
-	}
-
-	if len(parts) == 0 {
-		return baseURL + " + \"" + path + "\""
-	}
-	return baseURL + " + " + strings.Join(parts, " + ")
+	return fmt.Sprintf("%s + \"%s\"", baseURL, g.def.Request.Meta.Path)
 }
 
-func (g *contractGenerator) buildURLExpr(pathParams []string) string {
-	path := g.def.Request.Meta.Path
-	baseURL := g.cfg.BaseURLConst
-
-	if g.def.Request.Meta.BaseURL != "" {
-		baseURL = fmt.Sprintf("\"%s\"", g.def.Request.Meta.BaseURL)
-	}
-
-	if len(pathParams) == 0 {
-		return fmt.Sprintf("%s + \"%s\"", baseURL, path)
-	}
-
-	var parts []string
-	remaining := path
-	for _, p := range pathParams {
-		placeholder := "{" + p + "}"
-		idx := strings.Index(remaining, placeholder)
-		if idx == -1 {
-			continue
-		}
-		if idx > 0 {
-			parts = append(parts, fmt.Sprintf("\"%s\"", remaining[:idx]))
+// buildParamsExpr returns a map[string]any literal of params in the given key order, using
+// the custom source of a key if available, otherwise the parameter of the same name.
+func buildParamsExpr(keys []string, sources map[string]string) string {
+	entries := make([]string, 0, len(keys))
+	for _, k := range keys {
+		source, ok := sources[k]
+		if !ok {
+			source = k
 		}
-		parts = append(parts, p)
-		remaining = remaining[idx+len(placeholder):]
-	}
-	if remaining != "" {
-		parts = append(parts, fmt.Sprintf("\"%s\"", remaining))
-	}
-
-	if len(parts) == 0 {
-		return baseURL + " + \"" + path + "\""
+		entries = append(entries, fmt.Sprintf("%q: %s", k, source))
 	}
-	return baseURL + " + " + strings.Join(parts, " + ")
+	return "map[string]any{" + strings.Join(entries, ", ") + "}"
 }
 
 func (g *contractGenerator) generateStructBody(name string, data map[string]any) string {
@@ -85,6 +85,8 @@ func TestGenerateContract(t *testing.T) {
 		"var GetUser = func(",
 		"userId string",
 		"query *GetUserQuery",
+		`PathParams: map[string]any{"userId": userId}`,
+		"Query: query,",
 	}
 
 	for _, check := range checks {
@@ -135,6 +137,18 @@ func TestGenerateContract_InlineStruct(t *testing.T) {
 	if !strings.Contains(content, "struct {") {
 		t.Error("expected inline struct syntax")
 	}
+
+	// Outside package client, client identifiers are qualified and the body is built with JSONBody
+	checks := []string{
+		`import "github.com/vkviyu/nexus/transport/client"`,
+		"*client.Contract[CreateOrderResponse]",
+		"RequestBody: client.JSONBody(body)",
+	}
+	for _, check := range checks {
+		if !strings.Contains(content, check) {
+			t.Errorf("expected to contain '%s'", check)
+		}
+	}
 }
 
 func TestGenerateContractsFromDir(t *testing.T) {
@@ -389,8 +403,11 @@ func TestGenerateContract_CustomFuncParams(t *testing.T) {
 		t.Error("expected 'body *ClassBody' in function signature")
 	}
 
-	// Should use params.SchoolID in URL building
-	if !strings.Contains(content, "params.SchoolID") {
-		t.Error("expected 'params.SchoolID' in URL expression")
+	// Should use params.SchoolID as path parameter and the query fields as query parameters
+	if !strings.Contains(content, `PathParams: map[string]any{"schoolId": params.SchoolID}`) {
+		t.Error("expected 'params.SchoolID' in PathParams")
+	}
+	if !strings.Contains(content, `Query: map[string]any{"semester": params.Semester, "year": params.Year}`) {
+		t.Error("expected query fields in Query")
 	}
 }
\ No newline at end of file
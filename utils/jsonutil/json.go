@@ -5,13 +5,31 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// validate is shared by ValidateModel, a validator.Validate caches struct metadata and is
+// safe for concurrent use. Fields are named after their json tag, as sent by API clients,
+// and keep their Go name without one.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
 func ValidateModel[T any](model *T) error {
-	validator := validator.New()
-	return validator.Struct(model)
+	return validate.Struct(model)
 }
 
 func ParseJson[T any](data []byte) (*T, error) {